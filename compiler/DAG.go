@@ -2,6 +2,7 @@ package compiler
 
 import (
 	"bytes"
+	"complier/pkg/logger"
 	"complier/util"
 	"fmt"
 	"github.com/awalterschulze/gographviz"
@@ -32,6 +33,9 @@ type DAG struct {
 	NodeList     [][]*DAGNode       // 节点列表
 	CurrentList  []*DAGNode         // 当前节点列表
 	JmpMap       map[int]int        // 跳转语句映射,key为跳转语句在输入四元式中的编号，value为跳转语句在优化后的四元式中的编号
+	lines        map[int]int        // 输入四元式的编号->在输入中的行号
+	Logger       *logger.Logger     // 优化过程中发现的问题，如除数为0
+	Warnings     *WarningOptions    // 警告选项，与语义分析相同
 }
 
 // NewDAG 创建一个新的DAG
@@ -51,6 +55,9 @@ func NewDAG(qf *util.QuaFormList) *DAG {
 		NodeList:     make([][]*DAGNode, 0),
 		CurrentList:  make([]*DAGNode, 0),
 		JmpMap:       make(map[int]int),
+		lines:        make(map[int]int),
+		Logger:       logger.NewLogger(),
+		Warnings:     NewWarningOptions(),
 	}
 }
 
@@ -103,7 +110,7 @@ func (d *DAG) isFloat(s string) bool {
 func (d *DAG) parseQuaternions(input string) {
 	lines := strings.Split(input, "\n")
 
-	for _, line := range lines {
+	for n, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -122,7 +129,8 @@ func (d *DAG) parseQuaternions(input string) {
 				param[i] = parts[i]
 			}
 		}
-		d.Qf.AddQuaForm(param[0], param[1], param[2], param[3])
+		id := d.Qf.AddQuaForm(param[0], param[1], param[2], param[3])
+		d.lines[id] = n + 1
 	}
 	fmt.Println(d.Qf.PrintQuaFormList())
 }
@@ -229,7 +237,7 @@ func (d *DAG) optimizeBlock(block []*util.QuaForm) {
 	for _, qf := range block {
 		switch qf.Op {
 		case "+", "-", "*", "/", "%", "&", "|", "&&", "||", "<", ">", "<=", ">=", "==", "!=":
-			if d.isInt(qf.Arg1) && d.isInt(qf.Arg2) { // 如果两个操作数都是整数，则直接计算结果
+			if d.isInt(qf.Arg1) && d.isInt(qf.Arg2) && d.foldable(qf) { // 如果两个操作数都是整数，则直接计算结果
 				var result int
 				switch qf.Op {
 				case "+":
@@ -295,8 +303,8 @@ func (d *DAG) optimizeBlock(block []*util.QuaForm) {
 						result = 0
 					}
 				}
-				node := d.getOrAddNode(result)
-				d.addLabel(node, qf.Result) // 添加附加标签
+				node := d.getOrAddNode(int(int16(result))) // 与运行时一样只保留低16位
+				d.addLabel(node, qf.Result)                // 添加附加标签
 			} else {
 				left := d.getOrAddNode(qf.Arg1)
 				right := d.getOrAddNode(qf.Arg2)
@@ -322,8 +330,8 @@ func (d *DAG) optimizeBlock(block []*util.QuaForm) {
 						result = 0
 					}
 				}
-				node := d.getOrAddNode(result)
-				d.addLabel(node, qf.Result) // 添加附加标签
+				node := d.getOrAddNode(int(int16(result))) // 与运行时一样只保留低16位
+				d.addLabel(node, qf.Result)                // 添加附加标签
 			} else {
 				node := d.getOrAddNode(qf.Arg1)
 				existingNode := d.findNode(qf.Op, node, nil)
@@ -341,6 +349,25 @@ func (d *DAG) optimizeBlock(block []*util.QuaForm) {
 	d.generateOptimizedQuaForms()
 }
 
+// foldable 检查常数运算能否直接计算，除数为0时报告警告并且不计算，结果超出16位整数范围时报告警告
+func (d *DAG) foldable(qf *util.QuaForm) bool {
+	op, _ := qf.Op.(string)
+	x, y := qf.Arg1.(int), qf.Arg2.(int)
+	token := &util.TokenNode{Pos: util.Position{Line: d.lines[qf.Id], Column: 1}, Value: op}
+	switch op {
+	case "/", "%":
+		if y == 0 {
+			d.Warnings.report(d.Logger, WarnDivByZero, token, logger.CodeDivByZero, "除数为0: ", fmt.Sprintf("第%d条四元式", qf.Id))
+			return false
+		}
+	case "+", "-", "*":
+		if v, _ := foldInt(op, x, y); v < minInt16 || v > maxInt16 {
+			d.Warnings.report(d.Logger, WarnOverflow, token, logger.CodeOverflow, "整数运算溢出: ", fmt.Sprintf("%d %s %d = %d, 超出16位整数的范围", x, op, y, v))
+		}
+	}
+	return true
+}
+
 // getBlockId 根据四元式id获取基本块编号
 func (d *DAG) getBlockId(id int) int {
 	for i, block := range d.blocks {
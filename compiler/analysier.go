@@ -6,26 +6,36 @@ import (
 	"complier/util"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Param 函数参数
 type Param struct {
-	Type string
-	Name string
+	Type  string
+	Name  string
+	Token *util.TokenNode
 }
 
 // Info 符号表信息
 type Info struct {
-	Scope     string   //作用域范围的函数名
-	Name      string   //变量名
-	Type      string   //变量类型
-	Value     any      //变量值
-	Level     int      //变量作用域,0表示为全局
-	Pars      []string //如果是函数，需要参数列表
-	ParsName  []string //参数名
-	initFlag  bool     //标记当前info的value是否已经初始化
-	funcFlag  bool     //标记函数是否已经定义
-	ParamFlag bool     //标记是否是形参
+	Scope     string            //作用域范围的函数名
+	Name      string            //变量名
+	Type      string            //变量类型
+	Value     any               //变量值
+	Level     int               //变量作用域,0表示为全局
+	Pars      []string          //如果是函数，需要参数列表
+	ParsName  []string          //参数名
+	initFlag  bool              //标记当前info的value是否已经初始化
+	funcFlag  bool              //标记函数是否已经定义
+	ParamFlag bool              //标记是否是形参
+	builtin   bool              //标记是否是内置函数
+	variadic  bool              //标记函数是否接受可变个数的实参
+	constInit bool              //标记全局变量的初值是否为常量，常量初值直接写入数据段
+	declToken *util.TokenNode   //函数声明中函数名的位置
+	defToken  *util.TokenNode   //函数定义中函数名的位置
+	token     *util.TokenNode   //变量、常量声明中名字的位置
+	uses      []*util.TokenNode //被引用的位置，包括变量的读写、常量的读取和函数调用
+	order     int               //加入符号表的顺序
 }
 
 func (i *Info) Copy() *Info {
@@ -43,38 +53,127 @@ func (i *Info) Copy() *Info {
 
 // String 返回info的字符串形式
 func (i *Info) String() string {
-	str := fmt.Sprintf("%s\t\t\t%s\t\t%s\t\t%s\t\t%v", i.Scope, strconv.Itoa(i.Level), i.Name, i.Type, i.Value)
+	return i.row(i.Value)
+}
+
+// row 以value作为值返回info的字符串形式
+func (i *Info) row(value any) string {
+	str := fmt.Sprintf("%s\t\t\t%s\t\t%s\t\t%s\t\t%v", i.Scope, strconv.Itoa(i.Level), i.Name, i.Type, value)
 	if len(i.Pars) != 0 {
 		str += fmt.Sprintf("\t\t%v", i.Pars)
 	}
 	return str
 }
 
+// Field 结构体成员
+type Field struct {
+	Name   string            //成员名
+	Type   string            //成员类型
+	Offset int               //成员相对结构体起始地址的偏移
+	Size   int               //成员占用的字节数
+	token  *util.TokenNode   //成员名的位置
+	uses   []*util.TokenNode //被引用的位置
+}
+
+// TypeInfo 用户定义的结构体类型
+type TypeInfo struct {
+	Name   string            //类型名
+	Size   int               //结构体占用的字节数
+	Fields []*Field          //按声明顺序排列的成员
+	token  *util.TokenNode   //类型名的位置
+	order  int               //加入符号表的顺序
+	uses   []*util.TokenNode //类型名被引用的位置
+}
+
+// FindField 查找结构体成员
+func (t *TypeInfo) FindField(name string) (*Field, bool) {
+	for _, field := range t.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+// String 返回类型信息的字符串形式
+func (t *TypeInfo) String() string {
+	str := fmt.Sprintf("%s\t\t%d\t\t", t.Name, t.Size)
+	for _, field := range t.Fields {
+		str += fmt.Sprintf("%s %s(%d) ", field.Type, field.Name, field.Offset)
+	}
+	return str
+}
+
+// EnumInfo 用户定义的枚举类型
+type EnumInfo struct {
+	Name    string            //类型名
+	Members []string          //按声明顺序排列的枚举常量
+	Values  []int             //枚举常量的值
+	token   *util.TokenNode   //类型名的位置
+	order   int               //加入符号表的顺序
+	uses    []*util.TokenNode //类型名被引用的位置
+}
+
+// Range 返回枚举常量的最小值和最大值
+func (e *EnumInfo) Range() (int, int) {
+	min, max := e.Values[0], e.Values[0]
+	for _, v := range e.Values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return min, max
+}
+
+// String 返回枚举信息的字符串形式
+func (e *EnumInfo) String() string {
+	str := e.Name + "\t\t"
+	for i, name := range e.Members {
+		str += fmt.Sprintf("%s=%d ", name, e.Values[i])
+	}
+	return str
+}
+
 // SymbolTable 符号表
 type SymbolTable struct {
 	VarTable   map[string]map[string]*Info //变量表，作用域->变量名->变量信息
 	ConstTable map[string]map[string]*Info //常量表，作用域->常量名->常量信息
 	FuncTable  map[string]*Info            //函数表，函数名->函数信息
+	TypeTable  map[string]*TypeInfo        //类型表，类型名->结构体类型信息
+	EnumTable  map[string]*EnumInfo        //枚举表，类型名->枚举类型信息
+	blocks     []map[string]string         //分析函数时的块作用域链，每一层记录块内声明的变量名->变量表中的名字
+	seq        int                         //已加入符号表的符号个数，用于记录声明顺序
 }
 
-// String 返回符号表的字符串形式
+// String 返回符号表的字符串形式，作用域按全局、函数声明的顺序排列，同一作用域中按声明顺序排列
 func (s *SymbolTable) String() string {
 	str := "变量表: \n作用域\t作用域等级\t\t变量名\t变量类型\t变量值\n"
-	for _, table := range s.VarTable {
-		for _, v := range table {
-			str += v.String() + "\n"
-		}
+	for _, v := range s.scoped(s.VarTable) {
+		str += v.row(s.valueOf(v)) + "\n"
 	}
 	str += "\n\n常量表: \n作用域\t作用域等级\t\t常量名\t常量类型\t常量值\n"
-	for _, table := range s.ConstTable {
-		for _, v := range table {
-			str += v.String() + "\n"
-		}
+	for _, v := range s.scoped(s.ConstTable) {
+		str += v.String() + "\n"
 	}
 	str += "\n\n函数表: \n作用域\t作用域等级\t\t函数名\t函数类型\t函数值\t参数列表\n"
-	for _, v := range s.FuncTable {
+	for _, v := range s.funcs() {
 		str += v.String() + "\n"
 	}
+	if len(s.TypeTable) != 0 {
+		str += "\n\n类型表: \n类型名\t\t大小\t\t成员(偏移)\n"
+		for _, t := range s.types() {
+			str += t.String() + "\n"
+		}
+	}
+	if len(s.EnumTable) != 0 {
+		str += "\n\n枚举表: \n类型名\t\t枚举常量\n"
+		for _, e := range s.enums() {
+			str += e.String() + "\n"
+		}
+	}
 	return str
 
 }
@@ -85,17 +184,30 @@ func NewSymbolTable() *SymbolTable {
 		VarTable:   make(map[string]map[string]*Info),
 		ConstTable: make(map[string]map[string]*Info),
 		FuncTable:  make(map[string]*Info),
+		TypeTable:  make(map[string]*TypeInfo),
+		EnumTable:  make(map[string]*EnumInfo),
 	}
 
 }
 
 // AddVariable 添加变量
 func (s *SymbolTable) AddVariable(info *Info) {
+	info.order = s.next()
 	s.VarTable[info.Scope][info.Name] = info
 }
 
 // FindVariable 查找变量
 func (s *SymbolTable) FindVariable(scope string, name string) (*Info, bool) {
+	if len(s.blocks) != 0 && scope != consts.ALL { //分析函数时由内向外逐层查找块作用域，最后查找全局作用域
+		for i := len(s.blocks) - 1; i >= 0; i-- {
+			if key, ok := s.blocks[i][name]; ok {
+				info, found := s.VarTable[scope][key]
+				return info, found
+			}
+		}
+		info, found := s.VarTable[consts.ALL][name]
+		return info, found
+	}
 	info, found := s.VarTable[scope][name]
 	if !found { //如果在当前作用域找不到变量，就在全局作用域找
 		scope = consts.ALL
@@ -104,8 +216,78 @@ func (s *SymbolTable) FindVariable(scope string, name string) (*Info, bool) {
 	return info, found
 }
 
+// EnterFunction 进入函数作用域，函数的形参和函数体最外层的局部变量同属一个块
+func (s *SymbolTable) EnterFunction() {
+	s.blocks = []map[string]string{make(map[string]string)}
+}
+
+// ExitFunction 离开函数作用域，之后按变量表中的名字直接查找变量
+func (s *SymbolTable) ExitFunction() {
+	s.blocks = nil
+}
+
+// OpenBlock 进入复合语句形成的块作用域
+func (s *SymbolTable) OpenBlock() {
+	s.blocks = append(s.blocks, make(map[string]string))
+}
+
+// CloseBlock 离开块作用域，块内声明的变量不再可见
+func (s *SymbolTable) CloseBlock() {
+	if len(s.blocks) > 1 {
+		s.blocks = s.blocks[:len(s.blocks)-1]
+	}
+}
+
+// inBlock 判断变量是否已在最内层的块中声明
+func (s *SymbolTable) inBlock(name string) bool {
+	if len(s.blocks) == 0 {
+		return false
+	}
+	_, ok := s.blocks[len(s.blocks)-1][name]
+	return ok
+}
+
+// varKey 为局部变量分配在变量表中的名字，与同一函数中已有的变量或全局符号重名时加上@序号，
+// 使不同块中的同名变量在目标代码中分配到不同的存储单元
+func (s *SymbolTable) varKey(scope string, name string) string {
+	if len(s.blocks) == 0 {
+		return name
+	}
+	key := name
+	for i := 1; s.keyUsed(scope, key); i++ {
+		key = fmt.Sprintf("%s@%d", name, i)
+	}
+	return key
+}
+
+// keyUsed 判断名字是否已被函数中的变量、常量或者全局变量、全局常量占用
+func (s *SymbolTable) keyUsed(scope string, key string) bool {
+	for _, table := range []map[string]*Info{s.VarTable[scope], s.ConstTable[scope], s.VarTable[consts.ALL], s.ConstTable[consts.ALL]} {
+		if _, ok := table[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// declare 在最内层的块中登记变量
+func (s *SymbolTable) declare(name string, key string) {
+	if len(s.blocks) != 0 {
+		s.blocks[len(s.blocks)-1][name] = key
+	}
+}
+
+// sourceName 由变量表中的名字还原源程序中的变量名
+func sourceName(key string) string {
+	if i := strings.IndexByte(key, '@'); i > 0 {
+		return key[:i]
+	}
+	return key
+}
+
 // AddConstant 添加常量
 func (s *SymbolTable) AddConstant(info *Info) {
+	info.order = s.next()
 	s.ConstTable[info.Scope][info.Name] = info
 }
 
@@ -121,6 +303,7 @@ func (s *SymbolTable) FindConstant(scope string, name string) (*Info, bool) {
 
 // AddFunction 添加函数
 func (s *SymbolTable) AddFunction(info *Info) {
+	info.order = s.next()
 	s.FuncTable[info.Name] = info
 }
 
@@ -130,35 +313,104 @@ func (s *SymbolTable) FindFunction(name string) (*Info, bool) {
 	return info, found
 }
 
+// AddType 添加结构体类型
+func (s *SymbolTable) AddType(info *TypeInfo) {
+	info.order = s.next()
+	s.TypeTable[info.Name] = info
+}
+
+// FindType 查找结构体类型
+func (s *SymbolTable) FindType(name string) (*TypeInfo, bool) {
+	info, found := s.TypeTable[name]
+	return info, found
+}
+
+// AddEnum 添加枚举类型
+func (s *SymbolTable) AddEnum(info *EnumInfo) {
+	info.order = s.next()
+	s.EnumTable[info.Name] = info
+}
+
+// FindEnum 查找枚举类型
+func (s *SymbolTable) FindEnum(name string) (*EnumInfo, bool) {
+	info, found := s.EnumTable[name]
+	return info, found
+}
+
+// underlyingType 求类型参与运算时的类型，枚举类型按int处理
+func (s *SymbolTable) underlyingType(t string) string {
+	base := strings.TrimRight(t, "*")
+	if _, ok := s.FindEnum(base); ok {
+		return consts.TYPEINT + t[len(base):]
+	}
+	return t
+}
+
+// TypeSize 返回类型占用的字节数，基本类型占一个字，结构体为所有成员大小之和
+func (s *SymbolTable) TypeSize(t string) (int, bool) {
+	switch t {
+	case consts.TYPEINT, consts.TYPECHAR, consts.TYPEBOOL:
+		return 2, true
+	case consts.TYPEFLOAT: //单精度浮点数
+		return 4, true
+	}
+	if isPointer(t) {
+		return 2, true
+	}
+	if _, ok := s.FindEnum(t); ok { //枚举类型按int存储
+		return 2, true
+	}
+	if info, ok := s.FindType(t); ok {
+		return info.Size, true
+	}
+	return 0, false
+}
+
+// funcCall 程序中的一次函数调用
+type funcCall struct {
+	name   *util.TreeNode //调用中的函数名
+	caller string         //调用所在的函数
+}
+
 // Analyser 语义分析器
 type Analyser struct {
-	Ast           *util.TreeNode    //语法树
-	calStacks     *util.CalStacks   //运算栈
-	SymbolTable   *SymbolTable      //符号表
-	Logger        *logger.Logger    //日志记录器
-	Level         int               //作用域等级
-	Scope         string            //作用域
-	info          *Info             //当前传递的info信息
-	flag          bool              //标记当前传递的info信息是否已经完整
-	err           bool              //标记是否出现错误
-	paramFlag     bool              //标记是否有参数
-	divFlag       bool              //标记是否有除法
-	retFlag       bool              //标记是否有返回值
-	divToken      *util.TokenNode   //除法的token
-	node          *util.TreeNode    //当前节点
-	Qf            *util.QuaFormList //四元式列表
-	CurrentJmpPos *util.ForJmpPos   //当前循环的条件判断位置
-	currentFunc   string            //当前函数
-	params        []Param           //参数列表
+	Ast           *util.TreeNode            //语法树
+	calStacks     *util.CalStacks           //运算栈
+	SymbolTable   *SymbolTable              //符号表
+	Logger        *logger.Logger            //日志记录器
+	Level         int                       //作用域等级
+	Scope         string                    //作用域
+	info          *Info                     //当前传递的info信息
+	flag          bool                      //标记当前传递的info信息是否已经完整
+	err           bool                      //标记是否出现错误
+	paramFlag     bool                      //标记是否有参数
+	node          *util.TreeNode            //当前节点
+	Qf            *util.QuaFormList         //四元式列表
+	CurrentJmpPos *util.ForJmpPos           //当前循环的条件判断位置
+	currentFunc   string                    //当前函数
+	params        []Param                   //参数列表
+	structInfo    *TypeInfo                 //当前正在声明的结构体
+	enumInfo      *EnumInfo                 //当前正在声明的枚举
+	scaleTerms    map[*util.TreeNode]int    //指针运算中需要乘以元素大小的整数项
+	convNodes     map[*util.TreeNode]string //需要隐式转换的操作数及其目标类型
+	argTypes      map[*util.TreeNode]string //实参及其对应的形参类型
+	funcToken     *util.TokenNode           //当前函数定义中函数名的位置
+	calls         []funcCall                //程序中的函数调用，分析结束后检查被调用的函数是否已经定义
+	preMainFuncs  [][2]int                  //main之前定义的函数的四元式范围
+	Warnings      *WarningOptions           //警告选项
+	suppressed    map[int][]string          //被nowarn注释关闭的警告，行号->警告名，空字符串表示所有警告
+	reads         []varRead                 //表达式中对局部变量的读取，用于检查变量是否已经赋值
+	Xref          *Xref                     //交叉引用索引
 }
 
 // NewAnalyser 创建语义分析器
 func NewAnalyser(ast *util.TreeNode) *Analyser {
 	qf := util.NewQuaFormList()
+	symbolTable := NewSymbolTable()
 	return &Analyser{
 		Ast:         ast,
 		calStacks:   util.NewCalStacks(qf),
-		SymbolTable: NewSymbolTable(),
+		SymbolTable: symbolTable,
 		Logger:      logger.NewLogger(),
 		Level:       0,
 		Scope:       consts.ALL,
@@ -167,6 +419,8 @@ func NewAnalyser(ast *util.TreeNode) *Analyser {
 		err:         false,
 		node:        nil,
 		Qf:          qf,
+		Warnings:    NewWarningOptions(),
+		Xref:        newXref(symbolTable),
 	}
 }
 
@@ -192,11 +446,11 @@ func (a *Analyser) addConstTable() {
 
 	if a.info != nil {
 		if a.isExist(a.info.Name) {
-			a.Logger.AddErr("\t\t\t\t\t\t常量：" + a.info.Name + " 重复定义\n")
+			a.Logger.AddErr(logger.CodeRedeclaredConst, "常量重复定义: "+a.info.Name)
 			return
 		}
 		if a.info.Value == nil {
-			a.Logger.AddErr("\t\t\t\t\t\t常量：" + a.info.Name + " 未赋值\n")
+			a.Logger.AddErr(logger.CodeConstNoValue, "常量未赋值: "+a.info.Name)
 			return
 		}
 		a.SymbolTable.AddConstant(a.info)
@@ -211,8 +465,9 @@ func (a *Analyser) addVarTable() {
 		a.info = nil
 	}()
 	if a.info != nil {
-		if a.isExist(a.info.Name) {
-			a.Logger.AddErr("\t\t\t\t\t\t变量：" + a.info.Name + " 重复定义\n")
+		name := sourceName(a.info.Name)
+		if a.isRedeclared(name) {
+			a.Logger.AddErr(logger.CodeRedeclaredVar, "变量重复定义: "+name)
 			return
 		}
 		//TODO: 变量初始化?
@@ -225,12 +480,36 @@ func (a *Analyser) addVarTable() {
 		//		a.info.Value = ' '
 		//	}
 		//}
+		a.checkShadow(name, a.info.token)
 		a.info.Scope = a.Scope
 		a.info.Level = a.Level
 		a.SymbolTable.AddVariable(a.info)
+		a.SymbolTable.declare(name, a.info.Name)
 	}
 }
 
+// isRedeclared 检查变量是否重复定义，函数内只有同一个块中的同名变量、函数中的常量以及函数名算作重复定义，
+// 内层块的变量可以遮蔽外层块和全局的同名符号
+func (a *Analyser) isRedeclared(name string) bool {
+	if a.Scope == consts.ALL {
+		return a.isExist(name)
+	}
+	_, isConst := a.SymbolTable.ConstTable[a.Scope][name]
+	return a.SymbolTable.inBlock(name) || isConst || a.funcIsExist(name)
+}
+
+// varName 求变量在变量表中的名字，四元式中使用该名字区分不同块中的同名变量，同时记录一次对变量或常量的引用
+func (a *Analyser) varName(node *util.TreeNode) string {
+	if info, ok := a.SymbolTable.FindVariable(a.Scope, node.Value); ok {
+		a.Xref.add(info, node.Token)
+		return info.Name
+	}
+	if info, ok := a.SymbolTable.FindConstant(a.Scope, node.Value); ok {
+		a.Xref.add(info, node.Token)
+	}
+	return node.Value
+}
+
 // changeVarTable 修改变量表
 func (a *Analyser) changeVarTable() {
 	defer func() {
@@ -239,10 +518,11 @@ func (a *Analyser) changeVarTable() {
 	}()
 	if a.info != nil {
 		if !a.isExist(a.info.Name) {
-			a.Logger.AddErr("\t\t\t\t\t\t变量：" + a.info.Name + " 未定义\n")
+			a.Logger.AddErr(logger.CodeUndefinedVar, "变量未定义: "+a.info.Name)
 			return
 		}
-		info := a.SymbolTable.VarTable[a.Scope][a.info.Name].Copy()
+		v, _ := a.SymbolTable.FindVariable(a.Scope, a.info.Name)
+		info := v.Copy()
 		if info.Name != a.info.Value {
 			info.Value = a.info.Value
 			a.SymbolTable.AddVariable(info)
@@ -258,18 +538,61 @@ func (a *Analyser) addFuncTable() {
 		a.info = nil
 	}()
 	if a.isExist(a.info.Name) {
-		a.Logger.AddErr("\t\t\t\t\t\t函数：" + a.info.Name + " 重复定义\n")
+		a.Logger.AddErr(logger.CodeRedefinedFunc, "函数重复定义: "+a.info.Name)
 	} else {
 		a.SymbolTable.AddFunction(a.info)
 	}
 }
 
+// redeclareFunc 函数重复声明时检查返回类型和形参列表是否与之前的声明一致
+func (a *Analyser) redeclareFunc(prev *Info) {
+	defer func() {
+		a.flag = true
+		a.info = nil
+	}()
+	if prev.Type != a.info.Type || strings.Join(prev.Pars, ",") != strings.Join(a.info.Pars, ",") {
+		a.Logger.AddAnalyseErr(a.info.declToken, logger.CodeConflictingDecl, "函数声明与之前的声明不一致: ", funcSignature(a.info), " 与 ", funcSignature(prev)).WithRelated(prev.declToken, "函数声明位于此处")
+		a.err = true
+	}
+}
+
+// defineFunc 记录函数定义的位置，内置函数和已经定义过的函数不能再定义
+func (a *Analyser) defineFunc(info *Info) bool {
+	switch {
+	case info.builtin:
+		a.Logger.AddAnalyseErr(a.funcToken, logger.CodeRedefineBuiltin, "不能重新定义内置函数: ", info.Name)
+		a.err = true
+	case info.funcFlag:
+		a.Logger.AddAnalyseErr(a.funcToken, logger.CodeRedefinedFunc, "函数重复定义: ", info.Name).WithRelated(info.defToken, "之前的定义位于此处")
+		a.err = true
+	default:
+		info.funcFlag = true
+		info.defToken = a.funcToken
+		return true
+	}
+	return false
+}
+
+// checkCalledFuncs 检查被调用的函数是否都已经定义
+func (a *Analyser) checkCalledFuncs() {
+	for _, call := range a.calls {
+		if info, ok := a.SymbolTable.FindFunction(call.name.Value); ok && !info.funcFlag {
+			a.Logger.AddAnalyseErr(call.name.Token, logger.CodeFuncNotDefined, "函数已声明但未定义: ", call.name.Value).WithRelated(info.declToken, "函数声明位于此处")
+		}
+	}
+}
+
+// funcSignature 函数签名的字符串形式
+func funcSignature(info *Info) string {
+	return fmt.Sprintf("%s %s(%s)", info.Type, info.Name, strings.Join(info.Pars, ", "))
+}
+
 // TODO: 还要检查作用域
 // checkVar 在进行表达式运算时检查变量是否合法
 func (a *Analyser) checkVar(node *util.TreeNode) bool {
 	//一个变量可能是变量表中的变量，也可能是常量表中的常量
 	if !a.varIsExist(node.Value) && !a.constIsExist(node.Value) {
-		a.Logger.AddAnalyseErr(node.Token, "变量未定义")
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeUndefinedVar, "变量未定义")
 		return false
 	}
 	//TODO: 检查变量类型是否匹配
@@ -292,7 +615,7 @@ func (a *Analyser) checkVar(node *util.TreeNode) bool {
 	} else if a.constIsExist(node.Value) {
 		v, _ = a.SymbolTable.FindConstant(a.Scope, node.Value)
 	} else {
-		a.Logger.AddAnalyseErr(node.Token, "变量类型未知")
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeUnknownVarType, "变量类型未知")
 		return false
 	}
 
@@ -302,7 +625,7 @@ func (a *Analyser) checkVar(node *util.TreeNode) bool {
 	//}
 	//检查变量作用域,只有在同一作用域下或者在更高作用域下才能访问
 	if !(v.Level == 0 || v.Scope == a.info.Scope && v.Level <= a.info.Level) {
-		a.Logger.AddAnalyseErr(node.Token, "变量作用域不匹配")
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeScopeMismatch, "变量作用域不匹配")
 		return false
 	}
 	return true
@@ -327,10 +650,283 @@ func (a *Analyser) checkConstNumber(node *util.TreeNode) bool {
 	return true
 }
 
+// checkType 检查变量类型是否为基本类型或已声明的结构体，指针所指的类型可以是正在声明的结构体
+func (a *Analyser) checkType(node *util.TreeNode) bool {
+	t := varTypeOf(node)
+	base := strings.TrimRight(t, "*")
+	if base != t && a.structInfo != nil && base == a.structInfo.Name {
+		return true
+	}
+	if _, ok := a.SymbolTable.TypeSize(base); !ok {
+		a.Logger.AddAnalyseErr(node.Children[0].Token, logger.CodeUndefinedType, "类型未定义")
+		return false
+	}
+	return true
+}
+
+// memberAccessOf 取出变量节点后面的成员访问节点，没有成员访问时返回nil
+func memberAccessOf(node *util.TreeNode, next int) *util.TreeNode {
+	if next+1 < len(node.Children) && node.Children[next+1].Value == consts.MEMBER_ACCESS && isLegalNode(node.Children[next+1]) {
+		return node.Children[next+1]
+	}
+	return nil
+}
+
+// loadVar 变量入栈，读取结构体成员时先生成取成员的四元式，再将保存成员值的临时变量入栈
+func (a *Analyser) loadVar(node *util.TreeNode, access *util.TreeNode) {
+	if value, ok := a.enumValue(node); ok && access == nil { //枚举常量直接使用常数值
+		a.calStacks.PushNum(value)
+		return
+	}
+	_, offset, ok := a.checkField(node, access)
+	if !ok {
+		a.err = true
+		return
+	}
+	a.recordRead(node)
+	if access == nil {
+		a.calStacks.PushNum(a.varName(node))
+		return
+	}
+	result := a.Qf.GetTemp()
+	a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_FIELD], a.varName(node), offset, result)
+	a.calStacks.PushNum(result)
+}
+
+// storeVar 赋值语句的左值入栈，左值为结构体成员时入栈的是成员的引用
+func (a *Analyser) storeVar(node *util.TreeNode, access *util.TreeNode) {
+	_, offset, ok := a.checkField(node, access)
+	if !ok {
+		a.err = true
+		return
+	}
+	if access == nil {
+		a.calStacks.PushNum(a.varName(node))
+		return
+	}
+	a.calStacks.PushNum(&util.FieldRef{Name: a.varName(node), Offset: offset})
+}
+
+// loadAddress 取变量或结构体成员的地址，将保存地址的临时变量入栈
+func (a *Analyser) loadAddress(node *util.TreeNode, access *util.TreeNode) {
+	if !a.varIsExist(node.Value) {
+		if a.constIsExist(node.Value) {
+			a.Logger.AddAnalyseErr(node.Token, logger.CodeConstAddress, "常量不能取地址")
+		} else {
+			a.Logger.AddAnalyseErr(node.Token, logger.CodeUndefinedVar, "变量未定义")
+		}
+		a.err = true
+		return
+	}
+	if !a.checkVar(node) {
+		a.err = true
+		return
+	}
+	t, offset, errToken := a.resolveField(a.symbolType(node.Value), access)
+	if errToken != nil {
+		a.varName(node) //成员不存在时变量仍然算作被引用
+		a.Logger.AddAnalyseErr(errToken, logger.CodeNoSuchField, "结构体成员不存在: ", t+"."+errToken.Value)
+		a.err = true
+		return
+	}
+	var arg2 any
+	if access != nil {
+		arg2 = offset
+	}
+	result := a.Qf.GetTemp()
+	a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_ADDR], a.varName(node), arg2, result)
+	a.calStacks.PushNum(result)
+}
+
+// loadDeref 读取指针所指的值，先单独求出指针的值，再生成解引用的四元式
+func (a *Analyser) loadDeref(node *util.TreeNode) {
+	if !a.checkDeref(node) {
+		a.err = true
+		return
+	}
+	addr := a.evalSubExp(node, a.analyseFactor)
+	result := a.Qf.GetTemp()
+	a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_DEREF], addr, nil, result)
+	a.calStacks.PushNum(result)
+}
+
+// checkLvalue 检查赋值目标是否为变量，常量、枚举常量和函数名不能被赋值。
+// 按函数中的常量、局部变量、全局常量、全局变量的顺序查找，局部变量可以遮蔽同名的全局常量
+func (a *Analyser) checkLvalue(node *util.TreeNode) bool {
+	name := node.Value
+	if c, ok := a.SymbolTable.ConstTable[a.Scope][name]; ok {
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeAssignConst, "常量不可赋值: ", name).WithRelated(c.token, "常量定义位于此处")
+		return false
+	}
+	if v, ok := a.SymbolTable.FindVariable(a.Scope, name); ok && v.Scope != consts.ALL {
+		return true
+	}
+	if c, ok := a.SymbolTable.ConstTable[consts.ALL][name]; ok {
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeAssignConst, "常量不可赋值: ", name).WithRelated(c.token, "常量定义位于此处")
+		return false
+	}
+	if a.varIsExist(name) {
+		return true
+	}
+	if f, ok := a.SymbolTable.FindFunction(name); ok {
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeAssignFunc, "函数名不可赋值: ", name).WithRelated(f.declToken, "函数声明位于此处")
+		return false
+	}
+	a.Logger.AddAnalyseErr(node.Token, logger.CodeUndefinedVar, "变量未定义")
+	return false
+}
+
+// storeDeref 通过指针赋值时左值入栈，入栈的是指针所指单元的引用
+func (a *Analyser) storeDeref(node *util.TreeNode) {
+	if !a.checkDeref(node) {
+		a.err = true
+		return
+	}
+	addr := a.evalSubExp(node, a.analyseFactor)
+	a.calStacks.PushNum(&util.DerefRef{Addr: addr})
+}
+
+// checkDeref 检查解引用的操作数是否为指针，指向结构体的指针只能通过成员访问
+func (a *Analyser) checkDeref(node *util.TreeNode) bool {
+	t := a.exprType(node)
+	if t == "" {
+		return true
+	}
+	if !isPointer(t) {
+		a.Logger.AddAnalyseErr(firstToken(node), logger.CodeDerefNonPointer, "只能对指针解引用: ", t)
+		return false
+	}
+	if _, ok := a.SymbolTable.FindType(elemType(t)); ok {
+		a.Logger.AddAnalyseErr(firstToken(node), logger.CodeStructOperand, "结构体变量不能直接参与运算")
+		return false
+	}
+	return true
+}
+
+// analyseArithItem 分析算术表达式中的项，指针加减整数时整数项要先乘以指针所指类型的大小
+func (a *Analyser) analyseArithItem(node *util.TreeNode) {
+	size, ok := a.scaleTerms[node]
+	if !ok || size == 1 {
+		a.analyseOperand(node, a.analyseItem)
+		return
+	}
+	value := a.evalSubExp(node, a.analyseItem)
+	if n, err := strconv.Atoi(fmt.Sprint(value)); err == nil { //常数直接求出乘积
+		a.calStacks.PushNum(strconv.Itoa(n * size))
+		return
+	}
+	result := a.Qf.GetTemp()
+	a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_MUL], value, strconv.Itoa(size), result)
+	a.calStacks.PushNum(result)
+}
+
+// analyseCast 分析显式类型转换，显式转换不产生精度丢失的警告
+func (a *Analyser) analyseCast(typeNode *util.TreeNode, node *util.TreeNode) {
+	if !a.checkType(typeNode) {
+		a.err = true
+		return
+	}
+	to, from := varTypeOf(typeNode), a.exprType(node)
+	if !canConvert(from, to) {
+		a.Logger.AddAnalyseErr(typeNode.Children[0].Token, logger.CodeBadConversion, "无法进行类型转换: ", from, " -> ", to)
+		a.err = true
+		return
+	}
+	a.analyseConverted(node, to, a.analyseFactor, false)
+}
+
+// analyseOperand 分析运算的操作数，操作数需要隐式转换时转换为运算的公共类型
+func (a *Analyser) analyseOperand(node *util.TreeNode, analyse func(*util.TreeNode, int)) {
+	if to, ok := a.convNodes[node]; ok {
+		a.analyseConverted(node, to, analyse, false)
+		return
+	}
+	analyse(node, 0)
+}
+
+// analyseConverted 分析表达式并转换为目标类型，类型相同时直接分析，否则先单独求值再生成类型转换的四元式，
+// warn为true时对可能丢失精度的隐式转换给出警告
+func (a *Analyser) analyseConverted(node *util.TreeNode, to string, analyse func(*util.TreeNode, int), warn bool) {
+	from := a.exprType(node)
+	if warn {
+		a.warnNarrowing(node, from, to)
+	}
+	ops := convOps(from, to)
+	if len(ops) == 0 {
+		analyse(node, 0)
+		return
+	}
+	value := a.evalSubExp(node, analyse)
+	for _, op := range ops {
+		if isIntLiteral(value) { //整型常数直接写成转换后的常数
+			n, _ := strconv.Atoi(value.(string))
+			switch op {
+			case consts.QUA_ITOF:
+				value = fmt.Sprint(value) + ".0"
+				continue
+			case consts.QUA_ITOC:
+				value = strconv.Itoa(int(int8(n)))
+				continue
+			case consts.QUA_NE:
+				value = strconv.Itoa(boolInt(n != 0))
+				continue
+			}
+		}
+		result := a.Qf.GetTemp()
+		if op == consts.QUA_NE { //转换为bool时与0比较，结果为0或1
+			zero := "0"
+			if from == consts.TYPEFLOAT {
+				zero = "0.0"
+			}
+			a.Qf.AddQuaForm(consts.QuaFormMap[op], value, zero, result)
+		} else {
+			a.Qf.AddQuaForm(consts.QuaFormMap[op], value, nil, result)
+		}
+		value = result
+	}
+	a.calStacks.PushNum(value)
+}
+
+// markConversions 记录二元运算中类型低于公共类型、需要转换的操作数
+func (a *Analyser) markConversions(nodes ...*util.TreeNode) {
+	t := ""
+	types := make([]string, len(nodes))
+	for i, node := range nodes {
+		types[i] = a.exprType(node)
+		t = commonType(t, types[i])
+	}
+	if _, ok := typeRank[t]; !ok {
+		return
+	}
+	for i, node := range nodes {
+		if types[i] != "" && types[i] != t {
+			if a.convNodes == nil {
+				a.convNodes = make(map[*util.TreeNode]string)
+			}
+			a.convNodes[node] = t
+		}
+	}
+}
+
+// checkField 检查变量的成员访问是否合法，返回成员的类型和偏移，结构体变量只能通过成员参与运算
+func (a *Analyser) checkField(node *util.TreeNode, access *util.TreeNode) (string, int, bool) {
+	t, offset, errToken := a.resolveField(a.symbolType(node.Value), access)
+	if errToken != nil {
+		a.varName(node) //成员不存在时变量仍然算作被引用
+		a.Logger.AddAnalyseErr(errToken, logger.CodeNoSuchField, "结构体成员不存在: ", t+"."+errToken.Value)
+		return t, offset, false
+	}
+	if _, ok := a.SymbolTable.FindType(t); ok {
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeStructOperand, "结构体变量不能直接参与运算")
+		return t, offset, false
+	}
+	return t, offset, true
+}
+
 // checkFunc 在进行函数调用时检查函数是否合法
 func (a *Analyser) checkFunc(node *util.TreeNode) bool {
 	if !a.funcIsExist(node.Value) {
-		a.Logger.AddAnalyseErr(node.Token, "函数未定义")
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeUndefinedFunc, "函数未定义")
 		return false
 	}
 	//TODO: 检查函数参数是否匹配
@@ -347,7 +943,7 @@ func (a *Analyser) checkFunc(node *util.TreeNode) bool {
 // checkFuncCall 检查函数调用和定义时参数是否匹配
 func (a *Analyser) checkFuncParam(funcName string) bool {
 	if !a.funcIsExist(funcName) {
-		a.Logger.AddErr("\t\t\t\t\t\t函数：" + funcName + " 未定义\n")
+		a.Logger.AddErr(logger.CodeUndefinedFunc, "函数未定义: "+funcName)
 		return false
 	}
 	//if len(a.SymbolTable.FuncTable[funcName].Pars) != len(a.params) {
@@ -368,35 +964,47 @@ func (a *Analyser) checkFuncParam(funcName string) bool {
 // checkFuncParamList 检查函数参数列表,并将参数列表存入符号表
 func (a *Analyser) checkFuncParamList(funcName string) {
 	if !a.funcIsExist(funcName) {
-		a.Logger.AddErr("\t\t\t\t\t\t函数：" + funcName + " 未定义\n")
+		a.Logger.AddErr(logger.CodeUndefinedFunc, "函数未定义: "+funcName)
 		return
 	}
 	v, _ := a.SymbolTable.FindFunction(funcName)
-	if len(v.Pars) != len(a.params) {
-		a.Logger.AddErr("\t\t\t\t\t\t函数：" + funcName + " 参数个数不匹配\n")
+	if v.builtin { //内置函数没有形参表
 		return
 	}
-	for i, par := range v.Pars {
-		if par != a.params[i].Type {
-			a.Logger.AddErr("\t\t\t\t\t\t函数：" + funcName + fmt.Sprintf("%v", a.params) + " 参数类型不匹配，需要类型" + fmt.Sprintf("%v", v.Pars) + "\n")
-			return
-		}
+	types := make([]string, len(a.params))
+	for i, par := range a.params {
+		types[i] = par.Type
+	}
+	if v.declToken == a.funcToken { //函数定义同时作为声明，形参列表即为声明的形参列表
+		v.Pars = types
+	}
+	match := strings.Join(types, ",") == strings.Join(v.Pars, ",")
+	if !match { //形参仍然加入符号表，避免函数体中出现形参未定义的错误
+		a.Logger.AddAnalyseErr(a.funcToken, logger.CodeDeclParams, "函数形参与声明不一致: ", fmt.Sprintf("定义为(%s), 声明为(%s)", strings.Join(types, ", "), strings.Join(v.Pars, ", "))).WithRelated(v.declToken, "函数声明位于此处")
+	}
+	for i := range a.params {
 		name := a.params[i].Name
 		t := a.params[i].Type
-		if a.isExist(name) {
-			a.Logger.AddErr("\t\t\t\t\t\t变量：" + name + " 重复定义\n")
+		if a.isRedeclared(name) {
+			a.Logger.AddErr(logger.CodeRedeclaredVar, "变量重复定义: "+name)
 			return
 		}
+		a.checkShadow(name, a.params[i].Token)
 
+		key := a.SymbolTable.varKey(a.Scope, name)
 		a.SymbolTable.AddVariable(&Info{
 			Scope:     a.Scope,
 			Level:     a.info.Level + 1,
-			Name:      name,
+			Name:      key,
 			Type:      t,
 			Value:     a.info.Value,
 			ParamFlag: true, // 标记为形参
+			token:     a.params[i].Token,
 		})
-		v.ParsName = append(v.ParsName, name)
+		a.SymbolTable.declare(name, key)
+		if match {
+			v.ParsName = append(v.ParsName, key)
+		}
 	}
 }
 
@@ -426,9 +1034,6 @@ func (a *Analyser) constIsExist(name string) bool {
 
 // funcIsExist 检查函数是否存在
 func (a *Analyser) funcIsExist(name string) bool {
-	if name == "read" || name == "write" {
-		return true
-	}
 	if _, ok := a.SymbolTable.FindFunction(name); ok {
 		return true
 	}
@@ -492,7 +1097,18 @@ func (a *Analyser) StartAnalyse() {
 	// 初始化全局作用域
 	a.SymbolTable.VarTable[consts.ALL] = make(map[string]*Info)
 	a.SymbolTable.ConstTable[consts.ALL] = make(map[string]*Info)
+	a.SymbolTable.addBuiltins()
 	a.analyse(a.Ast, 0)
+	a.SymbolTable.ExitFunction()
+	a.checkCalledFuncs()
+	a.Xref.addTypeUses(a.Ast)
+	hasErr := len(a.Logger.Errs) > 0 //有错误时四元式不完整，不做数据流分析
+	a.checkUnused()
+	if !hasErr {
+		a.checkUninitialized()
+		a.checkConstArith()
+	}
+	a.moveFuncsAfterMain()
 }
 
 // analyse 递归遍历语法树进行语义分析
@@ -514,20 +1130,40 @@ func (a *Analyser) analyse(node *util.TreeNode, next int) {
 		// 初始化main函数作用域
 		a.SymbolTable.VarTable["main"] = make(map[string]*Info)
 		a.SymbolTable.ConstTable["main"] = make(map[string]*Info)
-		//添加main函数
+		//添加main函数，返回值作为程序的退出码
 		a.Qf.AddQuaForm("main", nil, nil, nil)
+		t := consts.TYPEVOID
+		if next > 0 && node.Children[next-1].Value == consts.MAIN_TYPE {
+			t = node.Children[next-1].Children[0].Value
+		}
 		a.SymbolTable.AddFunction(&Info{
-			Scope: consts.ALL,
-			Name:  "main",
-			Level: 0,
-			Type:  "void",
+			Scope:     consts.ALL,
+			Name:      "main",
+			Level:     0,
+			Type:      t,
+			funcFlag:  true,
+			declToken: child.Token,
+			defToken:  child.Token,
 		})
 		a.Scope = "main" //作用域为main函数
+		a.SymbolTable.EnterFunction()
 		a.currentFunc = "main"
 		a.info.Scope = a.Scope
 	case consts.COMPOUND_STMT:
 		a.analyseCompoundStatement(child, 0)
-		a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_SYS], nil, nil, nil)
+		a.checkFlow(child)
+		var status any
+		if info, _ := a.SymbolTable.FindFunction("main"); info.Type != consts.TYPEVOID { //int main没有return时退出码为0
+			status = "0"
+		}
+		a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_SYS], nil, nil, status)
+	case consts.FUNCTION_DEF: //main之前的函数定义，四元式在分析结束后移到main函数之后
+		start := len(a.Qf.QuaForms)
+		a.analyseFunctionDefine(child, 0)
+		a.preMainFuncs = append(a.preMainFuncs, [2]int{start, len(a.Qf.QuaForms)})
+		a.Scope = consts.ALL
+		a.currentFunc = ""
+		a.SymbolTable.ExitFunction()
 	case consts.FUNCTION_BLOCK:
 		a.analyseFunctionBlock(child, 0)
 	}
@@ -535,6 +1171,44 @@ func (a *Analyser) analyse(node *util.TreeNode, next int) {
 	a.analyse(node, next+1)
 }
 
+// moveFuncsAfterMain 将main之前定义的函数的四元式移到程序最后，使全局变量的初始化和main函数连续执行，同时修正跳转的目标
+func (a *Analyser) moveFuncsAfterMain() {
+	if len(a.preMainFuncs) == 0 {
+		return
+	}
+	forms := a.Qf.QuaForms
+	inFunc := make([]bool, len(forms))
+	for _, r := range a.preMainFuncs {
+		for i := r[0]; i < r[1]; i++ {
+			inFunc[i] = true
+		}
+	}
+	order := make([]int, 0, len(forms))
+	for _, moved := range []bool{false, true} {
+		for i := range forms {
+			if inFunc[i] == moved {
+				order = append(order, i)
+			}
+		}
+	}
+	newIndex := make(map[int]int, len(forms))
+	for i, old := range order {
+		newIndex[old] = i
+	}
+	moved := make([]*util.QuaForm, len(forms))
+	for i, old := range order {
+		form := forms[old]
+		if op, ok := form.Op.(string); ok && isTransferStatement(op) {
+			if target, ok := form.Result.(int); ok && target < len(forms) {
+				form.Result = newIndex[target]
+			}
+		}
+		form.Id = i
+		moved[i] = form
+	}
+	a.Qf.QuaForms = moved
+}
+
 // analyseDeclarationStatement 分析声明语句
 func (a *Analyser) analyseDeclarationStatement(node *util.TreeNode, next int) {
 	if next >= len(node.Children) || !isLegalNode(node) {
@@ -550,11 +1224,245 @@ func (a *Analyser) analyseDeclarationStatement(node *util.TreeNode, next int) {
 		a.analyseDeclarationValue(child, 0)
 	case consts.FUNCTION_DECL_STMT:
 		a.analyseDeclarationFunctionStatement(child, 0)
+	case consts.STRUCT_DECL:
+		a.analyseStructDeclaration(child, 0)
+	case consts.ENUM_DECL:
+		a.analyseEnumDeclaration(child, 0)
 	}
 	a.infoFlag()
 	a.analyseDeclarationStatement(node, next+1)
 }
 
+// analyseStructDeclaration 分析结构体声明
+func (a *Analyser) analyseStructDeclaration(node *util.TreeNode, next int) {
+	if next >= len(node.Children) || !isLegalNode(node) {
+		return
+	}
+	if a.info == nil {
+		a.initInfo()
+	}
+	child := node.Children[next]
+	switch child.Value {
+	case consts.VARIABLE:
+		if a.typeIsExist(child.Children[0].Value) {
+			a.Logger.AddAnalyseErr(child.Children[0].Token, logger.CodeRedefinedStruct, "结构体重复定义")
+		}
+		a.structInfo = &TypeInfo{Name: child.Children[0].Value, token: child.Children[0].Token}
+	case consts.STRUCT_MEMBERS:
+		a.analyseStructMembers(child, 0)
+	case ";":
+		//重复定义的结构体不覆盖之前的定义
+		if !a.typeIsExist(a.structInfo.Name) {
+			a.SymbolTable.AddType(a.structInfo)
+		}
+		a.structInfo = nil
+		a.flag = true
+	}
+	a.infoFlag()
+	a.analyseStructDeclaration(node, next+1)
+}
+
+// analyseStructMembers 分析结构体成员表
+func (a *Analyser) analyseStructMembers(node *util.TreeNode, next int) {
+	if next >= len(node.Children) || !isLegalNode(node) {
+		return
+	}
+	if a.info == nil {
+		a.initInfo()
+	}
+	child := node.Children[next]
+	switch child.Value {
+	case consts.VARIABLE_TYPE:
+		a.info.Type = varTypeOf(child)
+		a.checkType(child)
+	case consts.VARIABLE:
+		a.addStructField(child.Children[0])
+	case consts.STRUCT_MEMBERS_0:
+		a.analyseStructMembers0(child, 0)
+	}
+	a.infoFlag()
+	a.analyseStructMembers(node, next+1)
+}
+
+// analyseStructMembers0 分析结构体成员表0
+func (a *Analyser) analyseStructMembers0(node *util.TreeNode, next int) {
+	if next >= len(node.Children) || !isLegalNode(node) {
+		return
+	}
+	if a.info == nil {
+		a.initInfo()
+	}
+	child := node.Children[next]
+	switch child.Value {
+	case consts.STRUCT_MEMBERS:
+		a.analyseStructMembers(child, 0)
+	}
+	a.infoFlag()
+	a.analyseStructMembers0(node, next+1)
+}
+
+// addStructField 向当前结构体添加成员，成员按声明顺序依次排列，类型未定义或重复定义的成员被忽略
+func (a *Analyser) addStructField(node *util.TreeNode) {
+	size, ok := a.SymbolTable.TypeSize(a.info.Type)
+	if !ok {
+		return
+	}
+	if _, ok = a.structInfo.FindField(node.Value); ok {
+		a.Logger.AddAnalyseErr(node.Token, logger.CodeDuplicateField, "结构体成员重复定义")
+		return
+	}
+	a.structInfo.Fields = append(a.structInfo.Fields, &Field{
+		Name:   node.Value,
+		Type:   a.info.Type,
+		Offset: a.structInfo.Size,
+		Size:   size,
+		token:  node.Token,
+	})
+	a.structInfo.Size += size
+}
+
+// typeIsExist 检查结构体或枚举类型是否存在
+func (a *Analyser) typeIsExist(name string) bool {
+	if _, ok := a.SymbolTable.FindType(name); ok {
+		return true
+	}
+	_, ok := a.SymbolTable.FindEnum(name)
+	return ok
+}
+
+// analyseEnumDeclaration 分析枚举声明
+func (a *Analyser) analyseEnumDeclaration(node *util.TreeNode, next int) {
+	if next >= len(node.Children) || !isLegalNode(node) {
+		return
+	}
+	if a.info == nil {
+		a.initInfo()
+	}
+	child := node.Children[next]
+	switch child.Value {
+	case consts.VARIABLE:
+		if a.typeIsExist(child.Children[0].Value) {
+			a.Logger.AddAnalyseErr(child.Children[0].Token, logger.CodeRedefinedEnum, "枚举重复定义")
+		}
+		a.enumInfo = &EnumInfo{Name: child.Children[0].Value, token: child.Children[0].Token}
+	case consts.ENUM_MEMBERS:
+		a.analyseEnumMembers(child, 0)
+	case ";":
+		//重复定义的枚举不覆盖之前的定义
+		if !a.typeIsExist(a.enumInfo.Name) && len(a.enumInfo.Members) != 0 {
+			a.SymbolTable.AddEnum(a.enumInfo)
+		}
+		a.enumInfo = nil
+		a.flag = true
+	}
+	a.infoFlag()
+	a.analyseEnumDeclaration(node, next+1)
+}
+
+// analyseEnumMembers 分析枚举成员表
+func (a *Analyser) analyseEnumMembers(node *util.TreeNode, next int) {
+	if next >= len(node.Children) || !isLegalNode(node) {
+		return
+	}
+	if a.info == nil {
+		a.initInfo()
+	}
+	child := node.Children[next]
+	switch child.Value {
+	case consts.ENUM_VALUE:
+		a.addEnumMember(node.Children[next-1].Children[0], child)
+	case consts.ENUM_MEMBERS_0:
+		a.analyseEnumMembers0(child, 0)
+	}
+	a.infoFlag()
+	a.analyseEnumMembers(node, next+1)
+}
+
+// analyseEnumMembers0 分析枚举成员表0
+func (a *Analyser) analyseEnumMembers0(node *util.TreeNode, next int) {
+	if next >= len(node.Children) || !isLegalNode(node) {
+		return
+	}
+	if a.info == nil {
+		a.initInfo()
+	}
+	child := node.Children[next]
+	switch child.Value {
+	case consts.ENUM_MEMBERS:
+		a.analyseEnumMembers(child, 0)
+	}
+	a.infoFlag()
+	a.analyseEnumMembers0(node, next+1)
+}
+
+// addEnumMember 添加枚举常量，没有指定值时取前一个枚举常量的值加1，第一个枚举常量默认为0
+func (a *Analyser) addEnumMember(name *util.TreeNode, value *util.TreeNode) {
+	v := 0
+	if n := len(a.enumInfo.Values); n > 0 {
+		v = a.enumInfo.Values[n-1] + 1
+	}
+	if isLegalNode(value) {
+		c, err := a.evalConst(value.Children[1])
+		if err == nil && c.Type == consts.TYPEFLOAT {
+			err = &ConstErr{Token: firstToken(value.Children[1]), Code: logger.CodeEnumNotInt, Msg: "枚举值必须为整数"}
+		}
+		if err != nil {
+			a.Logger.AddAnalyseErr(err.Token, err.Code, err.Msg)
+			return
+		}
+		v = c.Int
+	}
+	if a.isExist(name.Value) {
+		a.Logger.AddAnalyseErr(name.Token, logger.CodeDuplicateEnumConst, "枚举常量重复定义")
+		return
+	}
+	a.enumInfo.Members = append(a.enumInfo.Members, name.Value)
+	a.enumInfo.Values = append(a.enumInfo.Values, v)
+	if a.typeIsExist(a.enumInfo.Name) { //重复定义的枚举不添加枚举常量
+		return
+	}
+	a.SymbolTable.AddConstant(&Info{
+		Scope:    a.Scope,
+		Level:    a.Level,
+		Name:     name.Value,
+		Type:     a.enumInfo.Name,
+		Value:    strconv.Itoa(v),
+		initFlag: true,
+		token:    name.Token,
+	})
+}
+
+// enumValue 查找枚举常量的值，同名的变量会遮蔽枚举常量。找到时记录一次对枚举常量的引用
+func (a *Analyser) enumValue(node *util.TreeNode) (string, bool) {
+	if a.varIsExist(node.Value) {
+		return "", false
+	}
+	info, ok := a.SymbolTable.FindConstant(a.Scope, node.Value)
+	if !ok {
+		return "", false
+	}
+	if _, ok = a.SymbolTable.FindEnum(info.Type); !ok {
+		return "", false
+	}
+	a.Xref.add(info, node.Token)
+	return info.Value.(string), true
+}
+
+// checkEnumRange 给枚举类型的变量赋常数值时，检查常数是否在枚举常量的取值范围内
+func (a *Analyser) checkEnumRange(t string, node *util.TreeNode) {
+	info, ok := a.SymbolTable.FindEnum(t)
+	if !ok {
+		return
+	}
+	v, ok := a.constIntValue(node)
+	if !ok {
+		return
+	}
+	if min, max := info.Range(); v < min || v > max {
+		a.warn(WarnEnumRange, firstToken(node), logger.CodeEnumRange, "枚举值超出范围: ", t, " ", strconv.Itoa(v))
+	}
+}
+
 // analyseDeclarationValue 分析值声明
 func (a *Analyser) analyseDeclarationValue(node *util.TreeNode, next int) {
 	if next >= len(node.Children) || !isLegalNode(node) {
@@ -608,6 +1516,7 @@ func (a *Analyser) analyseDeclarationConstTable(node *util.TreeNode, next int) {
 	switch child.Value {
 	case consts.VARIABLE:
 		a.info.Name = child.Children[0].Value
+		a.info.token = child.Children[0].Token
 		a.calStacks.PushNum(child.Children[0].Value) //变量入栈
 	case "=":
 		a.info.initFlag = true
@@ -704,26 +1613,21 @@ func (a *Analyser) analyseDeclarationConstTableValue(node *util.TreeNode, next i
 
 	child := node.Children[next]
 	switch child.Value {
-	case consts.VARIABLE:
-		//检查变量是否存在，类型是否匹配
-		if a.checkVar(child.Children[0]) {
-			v, _ := a.SymbolTable.FindVariable(a.Scope, child.Children[0].Value)
-			a.info.Value = v.Value            //取出变量值
-			a.calStacks.PushNum(a.info.Value) //变量值入栈
-		} else {
-			a.err = true
-		}
-		if a.info.Value == nil {
-			a.Logger.AddAnalyseErr(child.Children[0].Token, "常量未赋值")
+	case consts.BOOLEAN_EXPR:
+		//常量的值在编译期求出
+		v, err := a.evalConst(child)
+		if err != nil {
+			a.Logger.AddAnalyseErr(err.Token, err.Code, err.Msg)
 			a.err = true
+			break
 		}
-	case consts.CONSTANT:
-		if a.checkConstNumber(child.Children[0].Children[0]) {
-			a.info.Value = child.Children[0].Children[0].Value
-			a.calStacks.PushNum(child.Children[0].Children[0].Value) //常数入栈
-		} else {
-			a.err = true
+		t := a.SymbolTable.underlyingType(a.info.Type)
+		c := v.convert(t)
+		if v.changes(t) { //值在目标类型的范围内时不会丢失精度
+			a.warn(WarnNarrowing, firstToken(child), logger.CodeNarrowing, "隐式转换可能丢失精度: ", v.Type, " -> ", t)
 		}
+		a.info.Value = c.String()
+		a.calStacks.PushNum(a.info.Value) //常量值入栈
 	}
 	a.infoFlag()
 	a.analyseDeclarationConstTableValue(node, next+1)
@@ -740,7 +1644,10 @@ func (a *Analyser) analyseDeclarationVar(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case consts.VARIABLE_TYPE:
-		a.info.Type = child.Children[0].Value
+		a.info.Type = varTypeOf(child)
+		if !a.checkType(child) {
+			a.err = true
+		}
 	case consts.VARIABLE_TABLE:
 		a.analyseDeclarationVarTable(child, 0)
 	}
@@ -778,8 +1685,9 @@ func (a *Analyser) analyseDeclarationSingleVar(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case consts.VARIABLE:
-		a.info.Name = child.Children[0].Value
-		a.calStacks.PushNum(child.Children[0].Value) //变量名入栈
+		a.info.Name = a.SymbolTable.varKey(a.Scope, child.Children[0].Value)
+		a.info.token = child.Children[0].Token
+		a.calStacks.PushNum(a.info.Name) //变量名入栈
 	case consts.SINGLE_VARIABLE_0:
 		a.analyseDeclarationSingleVar0(child, 0)
 	}
@@ -798,15 +1706,55 @@ func (a *Analyser) analyseDeclarationSingleVar0(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case "=":
+		if _, ok := a.SymbolTable.FindType(a.info.Type); ok {
+			a.Logger.AddAnalyseErr(child.Token, logger.CodeStructInit, "结构体变量不能初始化")
+			a.err = true
+		}
 		a.info.initFlag = true
+		if a.Scope == consts.ALL && next+1 < len(node.Children) && a.globalConstInit(node.Children[next+1]) {
+			break
+		}
 		a.calStacks.PushOpe(consts.QUA_ASSIGNMENT)
 	case consts.BOOLEAN_EXPR:
-		a.analyseBoolExp(child, 0)
+		a.checkPointerAssign(a.SymbolTable.underlyingType(a.info.Type), child)
+		a.checkEnumRange(a.info.Type, child)
+		if a.info.constInit {
+			break
+		}
+		a.analyseConverted(child, a.SymbolTable.underlyingType(a.info.Type), a.analyseBoolExp, true)
 	}
 	a.infoFlag()
 	a.analyseDeclarationSingleVar0(node, next+1)
 }
 
+// globalConstInit 全局变量的初值为常量表达式时在编译期求值，不生成初始化的四元式
+func (a *Analyser) globalConstInit(node *util.TreeNode) bool {
+	if node.Value != consts.BOOLEAN_EXPR || isPointer(a.info.Type) {
+		return false
+	}
+	if _, ok := a.SymbolTable.FindType(a.info.Type); ok {
+		return false
+	}
+	v, err := a.evalConst(node)
+	if err != nil {
+		switch err.Code { //除数为0或溢出时在启动过程中求值，与函数中的表达式一样给出警告
+		case logger.CodeConstDivByZero:
+			a.warn(WarnDivByZero, err.Token, logger.CodeDivByZero, "除数为0")
+		case logger.CodeConstOverflow:
+			a.warn(WarnOverflow, err.Token, logger.CodeOverflow, err.Msg)
+		}
+		return false
+	}
+	t := a.SymbolTable.underlyingType(a.info.Type)
+	c := v.convert(t)
+	if v.changes(t) {
+		a.warn(WarnNarrowing, firstToken(node), logger.CodeNarrowing, "隐式转换可能丢失精度: ", v.Type, " -> ", t)
+	}
+	a.info.Value = c.String()
+	a.info.constInit = true
+	return true
+}
+
 // analyseDeclarationVarTable0 分析变量声明表0
 func (a *Analyser) analyseDeclarationVarTable0(node *util.TreeNode, next int) {
 	if next >= len(node.Children) || !isLegalNode(node) {
@@ -825,7 +1773,7 @@ func (a *Analyser) analyseDeclarationVarTable0(node *util.TreeNode, next int) {
 			//	a.calStacks.PushOpe(consts.QUA_ASSIGNMENT)
 			//	a.calStacks.PushNum(a.info.Value)
 			//}
-			if a.info.initFlag {
+			if a.info.initFlag && !a.info.constInit {
 				a.clearCalStacks()
 			} else {
 				a.calStacks.CurrentStack.NumStack.Pop()
@@ -845,7 +1793,7 @@ func (a *Analyser) analyseDeclarationVarTable0(node *util.TreeNode, next int) {
 			//	a.calStacks.PushOpe(consts.QUA_ASSIGNMENT)
 			//	a.calStacks.PushNum(a.info.Value)
 			//}
-			if a.info.initFlag {
+			if a.info.initFlag && !a.info.constInit {
 				a.clearCalStacks()
 			} else {
 				a.calStacks.CurrentStack.NumStack.Pop()
@@ -877,6 +1825,10 @@ func (a *Analyser) analyseBoolExp(node *util.TreeNode, next int) {
 	if a.info == nil {
 		a.initInfo()
 	}
+	if next == 0 && isTernaryExp(node) {
+		a.analyseTernaryExp(node)
+		return
+	}
 	child := node.Children[next]
 	switch child.Value {
 	case consts.BOOLEAN_ITEM:
@@ -888,6 +1840,108 @@ func (a *Analyser) analyseBoolExp(node *util.TreeNode, next int) {
 	a.analyseBoolExp(node, next+1)
 }
 
+// isTernaryExp 判断布尔表达式是否带有三目运算
+func isTernaryExp(node *util.TreeNode) bool {
+	last := node.Children[len(node.Children)-1]
+	return last.Value == consts.TERNARY_EXPR && isLegalNode(last)
+}
+
+// analyseTernaryExp 分析三目运算 c ? x : y，条件部分和if语句一样生成短路跳转，两个分支的值写入同一个临时变量
+func (a *Analyser) analyseTernaryExp(node *util.TreeNode) {
+	ternary := node.Children[len(node.Children)-1]
+	t1, t2 := a.exprType(ternary.Children[1]), a.exprType(ternary.Children[3])
+	if t1 == consts.TYPEVOID || t2 == consts.TYPEVOID || (t1 != "" && t2 != "" && commonType(t1, t2) == "") {
+		a.Logger.AddAnalyseErr(ternary.Children[0].Token, logger.CodeTernaryTypes, "三目运算两个分支的类型不兼容: ", t1, " -> ", t2)
+		a.err = true
+	}
+	if !isLegalNode(node.Children[1]) { //条件中有逻辑或时结果为bool，不需要检查
+		a.checkCondition(node.Children[0])
+	}
+	//两个分支都转换为公共类型
+	t := commonType(t1, t2)
+	branch := func(n *util.TreeNode, _ int) {
+		a.analyseConverted(n, t, a.analyseBoolExp, false)
+	}
+
+	// 条件可能出现在if语句的判断条件中，分析完三目运算后需要恢复现场
+	ifFlag, relaOp := a.Qf.IfFlag, a.Qf.RelaOp
+
+	//新建一个三目运算条件的计算栈
+	current := util.NewCalStack(a.Qf)
+	a.calStacks.LogicStack.Push(current.LogicStack)
+	a.calStacks.CurrentLogicStack = current.LogicStack
+	a.calStacks.BracketStack.Push(current)
+	a.calStacks.CurrentStack = current
+
+	a.Qf.IfFlag = true
+	a.Qf.RelaOp = false
+	a.calStacks.PushOpe(consts.QUA_LEFTSMALLBRACKET)
+	for _, child := range node.Children[:len(node.Children)-1] {
+		switch child.Value {
+		case consts.BOOLEAN_ITEM:
+			if a.exprType(child) == consts.TYPEFLOAT && !isLegalNode(node.Children[1]) { //浮点型的条件与0.0比较
+				a.analyseConverted(child, consts.TYPEBOOL, a.analyseBoolItem, false)
+			} else {
+				a.analyseBoolItem(child, 0)
+			}
+		case consts.BOOLEAN_EXPR_0:
+			a.analyseBoolExp0(child, 0)
+		}
+	}
+	a.calStacks.PushOpe(consts.QUA_RIGHTSMALLBRACKET)
+	a.calStacks.CalIf() //执行一次move操作，将括号算出的逻辑栈值移动到当前逻辑栈
+	//真出口为第一个分支
+	a.calStacks.ClearTrueStack(a.Qf.NextQuaFormId())
+	a.Qf.IfFlag = false
+	a.Qf.RelaOp = false
+
+	result := a.Qf.GetTemp()
+	a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_ASSIGNMENT], a.evalSubExp(ternary.Children[1], branch), nil, result)
+	//第一个分支结束后跳出三目运算，假出口为第二个分支
+	id := a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_JMP], nil, nil, nil)
+	a.calStacks.ClearFalseStack(a.Qf.NextQuaFormId())
+	a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_ASSIGNMENT], a.evalSubExp(ternary.Children[3], branch), nil, result)
+	a.Qf.QuaForms[id].Result = a.Qf.NextQuaFormId()
+
+	a.calStacks.PopCurrentLogicStack()
+	a.calStacks.CurrentLogicStack.ClearTrueStack(a.Qf.NextQuaFormId())
+	a.calStacks.CurrentLogicStack.ClearFalseStack(a.Qf.NextQuaFormId())
+	a.calStacks.PopCurrentLogicStack()
+	a.calStacks.BracketStack.Pop()
+	a.calStacks.CurrentStack = a.calStacks.BracketStack.Top().(*util.CalStack)
+
+	a.Qf.IfFlag, a.Qf.RelaOp = ifFlag, relaOp
+	a.calStacks.PushNum(result)
+}
+
+// analyseCondition 分析if、while、for语句的判断条件，浮点型的条件与0.0比较(!=)后作为bool
+func (a *Analyser) analyseCondition(node *util.TreeNode) {
+	a.checkCondition(node)
+	if a.exprType(node) == consts.TYPEFLOAT {
+		a.analyseConverted(node, consts.TYPEBOOL, a.analyseBoolExp, false)
+		return
+	}
+	a.analyseBoolExp(node, 0)
+}
+
+// evalSubExp 在新的计算栈中求出子表达式的值，用于三目运算的分支、指针运算的操作数等需要单独求值的场合
+func (a *Analyser) evalSubExp(node *util.TreeNode, analyse func(*util.TreeNode, int)) any {
+	ifFlag, relaOp := a.Qf.IfFlag, a.Qf.RelaOp
+	a.Qf.IfFlag = false
+	current := util.NewCalStack(a.Qf)
+	a.calStacks.BracketStack.Push(current)
+	a.calStacks.CurrentStack = current
+
+	analyse(node, 0)
+	current.CalAll()
+	value := current.NumStack.Top()
+
+	a.calStacks.BracketStack.Pop()
+	a.calStacks.CurrentStack = a.calStacks.BracketStack.Top().(*util.CalStack)
+	a.Qf.IfFlag, a.Qf.RelaOp = ifFlag, relaOp
+	return value
+}
+
 // analyseBoolItem 分析布尔项
 func (a *Analyser) analyseBoolItem(node *util.TreeNode, next int) {
 	if next >= len(node.Children) || !isLegalNode(node) {
@@ -936,10 +1990,14 @@ func (a *Analyser) analyseBoolFactor(node *util.TreeNode, next int) {
 	if a.info == nil {
 		a.initInfo()
 	}
+	if next == 0 && len(node.Children) > 1 && isLegalNode(node.Children[1]) { //关系运算的两个操作数转换为公共类型
+		a.markConversions(node.Children[0], node.Children[1].Children[1])
+		a.checkComparison(node)
+	}
 	child := node.Children[next]
 	switch child.Value {
 	case consts.ARITHMETIC_EXPR:
-		a.analyseArithmeticExp(child, 0)
+		a.analyseOperand(child, a.analyseArithmeticExp)
 	case consts.BOOLEAN_FACTOR_0:
 		a.analyseBoolFactor0(child, 0)
 	}
@@ -955,10 +2013,18 @@ func (a *Analyser) analyseArithmeticExp(node *util.TreeNode, next int) {
 	if a.info == nil {
 		a.initInfo()
 	}
+	if next == 0 {
+		a.checkPointerArith(node)
+		_, terms := arithTerms(node)
+		for _, term := range terms {
+			a.checkFloatMod(term)
+		}
+		a.markConversions(terms...)
+	}
 	child := node.Children[next]
 	switch child.Value {
 	case consts.TERM:
-		a.analyseItem(child, 0)
+		a.analyseArithItem(child)
 	case consts.ARITHMETIC_EXPR_0:
 		a.analyseArithmeticExp0(child, 0)
 	}
@@ -974,10 +2040,13 @@ func (a *Analyser) analyseItem(node *util.TreeNode, next int) {
 	if a.info == nil {
 		a.initInfo()
 	}
+	if next == 0 && isLegalNode(node.Children[1]) {
+		a.markConversions(termFactors(node)...)
+	}
 	child := node.Children[next]
 	switch child.Value {
 	case consts.FACTOR:
-		a.analyseFactor(child, 0)
+		a.analyseOperand(child, a.analyseFactor)
 	case consts.TERM_0:
 		a.analyseItem0(child, 0)
 	}
@@ -1003,13 +2072,13 @@ func (a *Analyser) analyseFactor(node *util.TreeNode, next int) {
 		a.analyseBoolExp(child, 0)
 	case consts.VARIABLE:
 		if a.checkVar(child.Children[0]) {
-			a.calStacks.PushNum(child.Children[0].Value)
+			a.loadVar(child.Children[0], memberAccessOf(node, next))
 		} else {
 			a.err = true
 		}
 	case consts.CONSTANT:
 		if a.checkConstNumber(child.Children[0].Children[0]) {
-			a.calStacks.PushNum(child.Children[0].Children[0].Value)
+			a.calStacks.PushNum(constValue(child.Children[0].Children[0]))
 		} else {
 			a.err = true
 		}
@@ -1038,6 +2107,15 @@ func (a *Analyser) analyseFactor0(node *util.TreeNode, next int) {
 		a.calStacks.PushOpe(consts.QUA_NEGATIVE)
 	case "!":
 		a.calStacks.PushOpe(consts.QUA_NOT)
+	case "*":
+		a.loadDeref(node.Children[next+1])
+		return
+	case "&":
+		a.loadAddress(node.Children[next+1].Children[0], memberAccessOf(node, next+1))
+		return
+	case "(":
+		a.analyseCast(node.Children[next+1], node.Children[next+3])
+		return
 	case consts.FACTOR:
 		a.analyseFactor(child, 0)
 	}
@@ -1056,25 +2134,13 @@ func (a *Analyser) analyseItem0(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case "*":
-		a.calStacks.PushOpe(consts.QUA_MUL)
+		a.calStacks.PushOpeAt(consts.QUA_MUL, child.Token)
 	case "/":
-		a.divFlag = true
-		a.divToken = child.Token
-		a.calStacks.PushOpe(consts.QUA_DIV)
+		a.calStacks.PushOpeAt(consts.QUA_DIV, child.Token)
 	case "%":
-		a.calStacks.PushOpe(consts.QUA_MOD)
+		a.calStacks.PushOpeAt(consts.QUA_MOD, child.Token)
 	case consts.FACTOR:
-		a.analyseFactor(child, 0)
-		if a.divFlag {
-			a.divFlag = false
-			if a.calStacks.CurrentStack.NumStack.Top() == "0" || a.calStacks.CurrentStack.NumStack.Top() == "0.0" {
-				a.Logger.AddAnalyseErr(a.divToken, "除数不能为0")
-			} else if info, ok := a.SymbolTable.FindVariable(a.Scope, a.calStacks.CurrentStack.NumStack.Top().(string)); ok && info.Value == "0" {
-				a.Logger.AddAnalyseErr(a.divToken, "除数不能为0")
-			} else if info, ok = a.SymbolTable.FindConstant(a.Scope, a.calStacks.CurrentStack.NumStack.Top().(string)); ok && info.Value == "0" {
-				a.Logger.AddAnalyseErr(a.divToken, "除数不能为0")
-			}
-		}
+		a.analyseOperand(child, a.analyseFactor)
 	case consts.TERM_0:
 		a.analyseItem0(child, 0)
 	}
@@ -1093,11 +2159,11 @@ func (a *Analyser) analyseArithmeticExp0(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case "+":
-		a.calStacks.PushOpe(consts.QUA_ADD)
+		a.calStacks.PushOpeAt(consts.QUA_ADD, child.Token)
 	case "-":
-		a.calStacks.PushOpe(consts.QUA_SUB)
+		a.calStacks.PushOpeAt(consts.QUA_SUB, child.Token)
 	case consts.TERM:
-		a.analyseItem(child, 0)
+		a.analyseArithItem(child)
 	case consts.ARITHMETIC_EXPR_0:
 		a.analyseArithmeticExp0(child, 0)
 	}
@@ -1118,7 +2184,7 @@ func (a *Analyser) analyseBoolFactor0(node *util.TreeNode, next int) {
 	case consts.RELATION_OPERATOR:
 		a.analyseRelationOperator(child, 0)
 	case consts.ARITHMETIC_EXPR:
-		a.analyseArithmeticExp(child, 0)
+		a.analyseOperand(child, a.analyseArithmeticExp)
 	}
 	a.infoFlag()
 	a.analyseBoolFactor0(node, next+1)
@@ -1185,7 +2251,9 @@ func (a *Analyser) analyseDeclarationFunctionStatement(node *util.TreeNode, next
 	child := node.Children[next]
 	switch child.Value {
 	case ";":
-		if !a.err {
+		if prev, ok := a.SymbolTable.FindFunction(a.info.Name); ok && !a.err && prev.declToken != nil {
+			a.redeclareFunc(prev) //重复的函数声明需要与之前的声明一致
+		} else if !a.err {
 			// 初始化函数作用域
 			a.SymbolTable.VarTable[a.info.Name] = make(map[string]*Info)
 			a.addFuncTable()
@@ -1212,6 +2280,7 @@ func (a *Analyser) analyseDeclarationFunction(node *util.TreeNode, next int) {
 		a.info.Type = child.Children[0].Value
 	case consts.VARIABLE:
 		a.info.Name = child.Children[0].Value
+		a.info.declToken = child.Children[0].Token
 	case consts.FUNCTION_PARAMS:
 		a.analyseDeclFormalParamList(child, 0)
 	}
@@ -1247,7 +2316,7 @@ func (a *Analyser) analyseDeclFormalParam(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case consts.VARIABLE_TYPE:
-		a.info.Pars = append(a.info.Pars, child.Children[0].Value)
+		a.info.Pars = append(a.info.Pars, varTypeOf(child))
 		//a.info.Type = child.Children[0].Value
 	case consts.FUNCTION_PARAM_0:
 		a.analyseDeclFormalParam0(child, 0)
@@ -1286,7 +2355,13 @@ func (a *Analyser) analyseCompoundStatement(node *util.TreeNode, next int) {
 	case "{":
 		a.Level++
 		a.info.Level = a.Level
+		if a.Level > 1 { //函数体与形参同属一个块，内层的复合语句才形成新的块
+			a.SymbolTable.OpenBlock()
+		}
 	case "}":
+		if a.Level > 1 {
+			a.SymbolTable.CloseBlock()
+		}
 		a.Level--
 		a.info.Level = a.Level
 	case consts.STATEMENT_TABLE:
@@ -1560,7 +2635,7 @@ func (a *Analyser) analyseIfStatement(node *util.TreeNode, next int) {
 		a.Qf.IfFlag = false
 		a.Qf.RelaOp = false
 	case consts.BOOLEAN_EXPR:
-		a.analyseBoolExp(child, 0)
+		a.analyseCondition(child)
 	case consts.COMPOUND_STMT:
 		a.analyseCompoundStatement(child, 0)
 	case consts.IF_TAIL:
@@ -1657,7 +2732,7 @@ func (a *Analyser) analyseWhileStatement(node *util.TreeNode, next int) {
 		a.calStacks.ClearTrueStack(a.Qf.NextQuaFormId())
 		a.Qf.IfFlag = false
 	case consts.BOOLEAN_EXPR:
-		a.analyseBoolExp(child, 0)
+		a.analyseCondition(child)
 	case consts.COMPOUND_STMT:
 		a.analyseCompoundStatement(child, 0)
 		//while语句结束，跳回到while的判断条件，然后回填假出口
@@ -1701,7 +2776,7 @@ func (a *Analyser) analyseDoWhileStatement(node *util.TreeNode, next int) {
 		a.flag = true
 		a.calStacks.PopCurrentLogicStack()
 	case consts.BOOLEAN_EXPR:
-		a.analyseBoolExp(child, 0)
+		a.analyseCondition(child)
 	case consts.COMPOUND_STMT:
 		//记录语句开始的位置
 		a.CurrentJmpPos.ConditionPos = a.Qf.NextQuaFormId()
@@ -1747,7 +2822,7 @@ func (a *Analyser) analyseForStatement(node *util.TreeNode, next int) {
 			a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_JMP], nil, nil, a.CurrentJmpPos.ConditionPos)
 		}
 	case consts.BOOLEAN_EXPR:
-		a.analyseBoolExp(child, 0)
+		a.analyseCondition(child)
 		a.calStacks.PushOpe(consts.QUA_RIGHTSMALLBRACKET)
 		a.calStacks.CalIf() //执行一次move操作，将括号算出的逻辑栈值移动到当前逻辑栈
 		//a.calStacks.CurrentStack.OpStack.Pop()  // 弹出move操作
@@ -1782,6 +2857,11 @@ func (a *Analyser) analyseBreak(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case "break":
+		if a.Qf.BreakStacks.IsEmpty() {
+			a.Logger.AddAnalyseErr(child.Token, logger.CodeBreakOutsideLoop, "break语句不在循环中")
+			a.err = true
+			break
+		}
 		//break跳转的位置是需要回填的
 		id := a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_JMP], nil, nil, nil)
 		a.Qf.CurrentBreakStack.Push(id)
@@ -1801,12 +2881,17 @@ func (a *Analyser) analyseContinue(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case "continue":
+		if a.Qf.ContinueStacks.IsEmpty() {
+			a.Logger.AddAnalyseErr(child.Token, logger.CodeContinueOutsideLoop, "continue语句不在循环中")
+			a.err = true
+			break
+		}
 		//continue跳转的位置是需要回填的
 		id := a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_JMP], nil, nil, nil)
 		a.Qf.CurrentContinueStack.Push(id)
 	}
 	a.infoFlag()
-	a.analyseBreak(node, next+1)
+	a.analyseContinue(node, next+1)
 }
 
 // analyseReturn 分析return语句
@@ -1820,8 +2905,8 @@ func (a *Analyser) analyseReturn(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case "return":
-		if a.Level == 1 {
-			a.retFlag = true
+		if next+1 < len(node.Children) {
+			a.checkReturn(child.Token, node.Children[next+1])
 		}
 	case consts.RETURN_STMT_0:
 		a.analyseReturn0(child, 0)
@@ -1841,15 +2926,23 @@ func (a *Analyser) analyseReturn0(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case ";":
+		op := consts.QuaFormMap[consts.QUA_RETURN]
+		if a.currentFunc == "main" { //main函数返回时结束程序，返回值作为退出码
+			op = consts.QuaFormMap[consts.QUA_SYS]
+		}
 		if next == 0 {
-			a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_RETURN], nil, nil, nil)
+			a.Qf.AddQuaForm(op, nil, nil, nil)
 		} else {
 			a.calStacks.CalAllUtilReturn()
-			a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_RETURN], nil, nil, a.calStacks.Result)
+			a.Qf.AddQuaForm(op, nil, nil, a.calStacks.Result)
 		}
 	case consts.BOOLEAN_EXPR:
 		a.calStacks.CurrentStack.OpStack.Push(consts.QUA_RETURN)
-		a.analyseBoolExp(child, 0)
+		t := ""
+		if info, ok := a.SymbolTable.FindFunction(a.currentFunc); ok {
+			t = info.Type
+		}
+		a.analyseConverted(child, t, a.analyseBoolExp, true) //返回值转换为函数的返回类型
 	}
 	a.infoFlag()
 	a.analyseReturn0(node, next+1)
@@ -1913,7 +3006,7 @@ func (a *Analyser) analyseFuncCall(node *util.TreeNode, next int, flag bool) {
 	case consts.VARIABLE:
 		if flag { //在函数调用语句中
 			if !a.funcIsExist(child.Children[0].Value) {
-				a.Logger.AddAnalyseErr(child.Children[0].Token, "函数未定义")
+				a.Logger.AddAnalyseErr(child.Children[0].Token, logger.CodeUndefinedFunc, "函数未定义")
 				a.err = true
 			} else {
 				a.info.Name = child.Children[0].Value
@@ -1926,10 +3019,19 @@ func (a *Analyser) analyseFuncCall(node *util.TreeNode, next int, flag bool) {
 			} else {
 				a.err = true
 			}
+			if info, ok := a.SymbolTable.FindFunction(child.Children[0].Value); ok && info.Type == consts.TYPEVOID {
+				a.Logger.AddAnalyseErr(child.Children[0].Token, logger.CodeVoidOperand, "没有返回值的函数不能参与运算")
+				a.err = true
+			}
 		}
 	case "(":
 
 	case consts.ARGUMENTS:
+		a.calls = append(a.calls, funcCall{name: node.Children[0].Children[0], caller: a.currentFunc})
+		if f, ok := a.SymbolTable.FindFunction(node.Children[0].Children[0].Value); ok {
+			a.Xref.add(f, node.Children[0].Children[0].Token)
+		}
+		a.checkArgs(node.Children[0].Children[0], child)
 		if child.Children[0].Value != consts.NULL {
 			a.calStacks.PushOpe(consts.QUA_PARAM)
 		}
@@ -1975,7 +3077,13 @@ func (a *Analyser) analyseActualParam(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case consts.BOOLEAN_EXPR:
-		a.analyseBoolExp(child, 0)
+		if to, ok := a.argTypes[child]; ok { //实参转换为形参的类型
+			a.analyseConverted(child, to, a.analyseBoolExp, true)
+		} else {
+			a.analyseBoolExp(child, 0)
+		}
+	case consts.STRING_CONSTANT:
+		a.calStacks.PushNum(child.Children[0].Value) //字符串常量入栈，目标代码中传递其地址
 	case consts.ARGUMENT_0:
 		a.analyseActualParam0(child, 0)
 	}
@@ -2013,26 +3121,22 @@ func (a *Analyser) analyseAssignmentExp(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case consts.VARIABLE:
-		if a.constIsExist(child.Children[0].Value) {
+		if a.checkLvalue(child.Children[0]) && a.checkVar(child.Children[0]) {
 			a.info.Name = child.Children[0].Value
-			a.Logger.AddAnalyseErr(child.Children[0].Token, "常量不可赋值")
-			a.err = true
-		} else if !a.varIsExist(child.Children[0].Value) {
-			a.Logger.AddAnalyseErr(child.Children[0].Token, "变量未定义")
-			a.err = true
+			a.storeVar(child.Children[0], memberAccessOf(node, next))
 		} else {
-			if a.checkVar(child.Children[0]) {
-				a.info.Name = child.Children[0].Value
-				a.calStacks.PushNum(child.Children[0].Value)
-			} else {
-				a.err = true
-			}
-
+			a.err = true
 		}
+	case "*":
+		a.storeDeref(node.Children[next+1])
 	case "=":
 		a.calStacks.PushOpe(consts.QUA_ASSIGNMENT)
 	case consts.BOOLEAN_EXPR:
-		a.analyseBoolExp(child, 0)
+		t := a.lvalueType(node)
+		a.checkEnumRange(t, child)
+		t = a.SymbolTable.underlyingType(t)
+		a.checkPointerAssign(t, child)
+		a.analyseConverted(child, t, a.analyseBoolExp, true)
 	}
 	a.infoFlag()
 	a.analyseAssignmentExp(node, next+1)
@@ -2070,8 +3174,17 @@ func (a *Analyser) analyseFunctionDefine(node *util.TreeNode, next int) {
 	case consts.FUNCTION_TYPE:
 		a.info.Type = child.Children[0].Value
 	case consts.VARIABLE:
+		if !a.isExist(child.Children[0].Value) { //没有函数声明时，函数定义同时作为声明
+			a.SymbolTable.VarTable[child.Children[0].Value] = make(map[string]*Info)
+			a.SymbolTable.AddFunction(&Info{
+				Scope:     consts.ALL,
+				Name:      child.Children[0].Value,
+				Type:      a.info.Type,
+				declToken: child.Children[0].Token,
+			})
+		}
 		if !a.funcIsExist(child.Children[0].Value) {
-			a.Logger.AddAnalyseErr(child.Children[0].Token, "函数未声明")
+			a.Logger.AddAnalyseErr(child.Children[0].Token, logger.CodeUndeclaredFunc, "函数未声明")
 			a.err = true
 		} else {
 			a.info.Name = child.Children[0].Value
@@ -2079,9 +3192,11 @@ func (a *Analyser) analyseFunctionDefine(node *util.TreeNode, next int) {
 			a.Qf.AddQuaForm(a.info.Name, nil, nil, nil)
 			info, _ := a.SymbolTable.FindFunction(a.info.Name)
 			a.Scope = a.info.Name
+			a.SymbolTable.EnterFunction()
 			a.info.Scope = a.Scope
-			if info.Type != a.info.Type {
-				a.Logger.AddAnalyseErr(child.Children[0].Token, "函数返回类型不匹配")
+			a.funcToken = child.Children[0].Token
+			if a.defineFunc(info) && info.Type != a.info.Type {
+				a.Logger.AddAnalyseErr(a.funcToken, logger.CodeDeclReturnType, "函数返回类型与声明不一致: ", fmt.Sprintf("定义为%s, 声明为%s", a.info.Type, info.Type)).WithRelated(info.declToken, "函数声明位于此处")
 				a.err = true
 			}
 		}
@@ -2090,8 +3205,9 @@ func (a *Analyser) analyseFunctionDefine(node *util.TreeNode, next int) {
 	case ")":
 		if a.paramFlag {
 			a.params = append(a.params, Param{
-				Name: a.info.Name,
-				Type: a.info.Type,
+				Name:  a.info.Name,
+				Type:  a.info.Type,
+				Token: a.info.token,
 			})
 		}
 		a.checkFuncParamList(a.currentFunc) //检查函数参数类型是否匹配，并加入符号表
@@ -2105,16 +3221,14 @@ func (a *Analyser) analyseFunctionDefine(node *util.TreeNode, next int) {
 		a.analyseDefineFormalParamList(child, 0)
 	case consts.COMPOUND_STMT:
 		a.analyseCompoundStatement(child, 0)
-		if !a.retFlag {
-			f, _ := a.SymbolTable.FindFunction(a.currentFunc)
-			if f.Type == "void" {
+		if a.checkFlow(child) { //有路径执行到函数结尾
+			if f, ok := a.SymbolTable.FindFunction(a.currentFunc); ok && f.Type == consts.TYPEVOID {
 				a.Qf.AddQuaForm(consts.QuaFormMap[consts.QUA_RETURN], nil, nil, nil)
-			} else {
-				a.Logger.AddErr("\t\t\t\t\t\t函数：" + a.currentFunc + " 缺少返回语句\n")
+			} else if ok {
+				a.Logger.AddAnalyseErr(a.funcToken, logger.CodeMissingReturnStmt, "函数缺少返回语句: ", a.currentFunc)
 				a.err = true
 			}
 		}
-		a.retFlag = false
 	}
 	a.infoFlag()
 	a.analyseFunctionDefine(node, next+1)
@@ -2149,9 +3263,10 @@ func (a *Analyser) analyseDefineFormalParam(node *util.TreeNode, next int) {
 	child := node.Children[next]
 	switch child.Value {
 	case consts.VARIABLE_TYPE:
-		a.info.Type = child.Children[0].Value
+		a.info.Type = varTypeOf(child)
 	case consts.VARIABLE:
 		a.info.Name = child.Children[0].Value
+		a.info.token = child.Children[0].Token
 	case consts.FUNCTION_PARAM_0_DEF:
 		a.analyseDefineFormalParam0(child, 0)
 	}
@@ -2171,8 +3286,9 @@ func (a *Analyser) analyseDefineFormalParam0(node *util.TreeNode, next int) {
 	switch child.Value {
 	case ",":
 		a.params = append(a.params, Param{
-			Name: a.info.Name,
-			Type: a.info.Type,
+			Name:  a.info.Name,
+			Type:  a.info.Type,
+			Token: a.info.token,
 		})
 	case consts.FUNCTION_PARAM_DEF:
 		a.analyseDefineFormalParam(child, 0)
@@ -0,0 +1,170 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// builtins 内置函数及其签名，由目标代码中的运行时库实现
+var builtins = []*Info{
+	{Name: "read", Type: consts.TYPEINT},
+	{Name: "write", Type: consts.TYPEVOID, Pars: []string{consts.TYPEINT}},
+	{Name: "writec", Type: consts.TYPEVOID, Pars: []string{consts.TYPECHAR}},
+	{Name: "writeln", Type: consts.TYPEVOID},
+	{Name: "prints", Type: consts.TYPEVOID, Pars: []string{consts.TYPESTR}},
+	{Name: "printf", Type: consts.TYPEVOID, Pars: []string{consts.TYPESTR}, variadic: true},
+}
+
+// runtimeProcs 内置函数对应的运行时过程，按生成的顺序排列
+var runtimeProcs = []struct {
+	Name  string
+	Code  string
+	Data  string //过程用到的数据段定义
+	Print bool   //是否用到_print
+	FPU   bool   //是否用到8087
+}{
+	{"read", consts.ASM_READ, consts.ASM_READ_DATA, true, false},
+	{"write", consts.ASM_WRITE, "", true, false},
+	{"writef", consts.ASM_WRITEF, "", true, true},
+	{"writec", consts.ASM_WRITEC, "", false, false},
+	{"writeln", consts.ASM_WRITELN, "", false, false},
+	{"prints", consts.ASM_PRINTS, "", true, false},
+	{"printf", consts.ASM_PRINTF, "", false, true},
+}
+
+// addBuiltins 在全局作用域中预先声明内置函数
+func (s *SymbolTable) addBuiltins() {
+	for _, b := range builtins {
+		s.AddFunction(&Info{
+			Scope:    consts.ALL,
+			Name:     b.Name,
+			Type:     b.Type,
+			Pars:     b.Pars,
+			funcFlag: true,
+			builtin:  true,
+			variadic: b.variadic,
+		})
+	}
+}
+
+// argList 将实参列表展开为实参的列表，实参为布尔表达式或字符串常量
+func argList(node *util.TreeNode) []*util.TreeNode {
+	var args []*util.TreeNode
+	for arg := node.Children[0]; isLegalNode(arg); {
+		args = append(args, arg.Children[0])
+		rest := arg.Children[1]
+		if !isLegalNode(rest) {
+			break
+		}
+		arg = rest.Children[1]
+	}
+	return args
+}
+
+// checkArgs 检查实参的个数和类型是否与函数的形参匹配
+func (a *Analyser) checkArgs(fn *util.TreeNode, node *util.TreeNode) {
+	info, ok := a.SymbolTable.FindFunction(fn.Value)
+	if !ok {
+		return
+	}
+	args := argList(node)
+	if info.variadic && len(args) >= len(info.Pars) {
+		a.checkFormat(args[0], args[1:])
+		args = args[:len(info.Pars)]
+	}
+	if len(args) != len(info.Pars) {
+		a.Logger.AddAnalyseErr(fn.Token, logger.CodeArgCount, "参数个数不匹配: ", fmt.Sprintf("需要%d个, 实际为%d个", len(info.Pars), len(args))).WithRelated(info.declToken, "函数声明位于此处")
+		a.err = true
+		return
+	}
+	for i, arg := range args {
+		par := info.Pars[i]
+		t := consts.TYPESTR
+		if arg.Value != consts.STRING_CONSTANT {
+			t = a.exprType(arg)
+		}
+		if t == "" || t == par {
+			continue
+		}
+		if t == consts.TYPESTR || par == consts.TYPESTR || !canConvert(t, par) {
+			a.Logger.AddAnalyseErr(firstToken(arg), logger.CodeArgType, "参数类型不匹配: ", t, " -> ", par).WithRelated(info.declToken, "函数声明位于此处")
+			a.err = true
+			continue
+		}
+		a.checkPointerAssign(par, arg)
+		if info.builtin && info.Name == "write" && t == consts.TYPEFLOAT { //write输出浮点数时调用writef，按实参的实际类型传递
+			continue
+		}
+		if a.argTypes == nil {
+			a.argTypes = make(map[*util.TreeNode]string)
+		}
+		a.argTypes[arg] = par
+	}
+}
+
+// checkFormat 检查格式串中的格式说明符与其余实参的个数和类型是否匹配
+// %d和%c对应整型、字符型或布尔型，%f对应浮点型，%s对应字符串常量，%%输出%本身
+func (a *Analyser) checkFormat(format *util.TreeNode, args []*util.TreeNode) {
+	if format.Value != consts.STRING_CONSTANT {
+		return
+	}
+	token := format.Children[0].Token
+	s, err := strconv.Unquote(format.Children[0].Value)
+	if err != nil {
+		s = strings.Trim(format.Children[0].Value, "\"")
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		i++
+		if i == len(s) {
+			a.Logger.AddAnalyseErr(token, logger.CodeBadFormat, "无效的格式说明符: ", "%")
+			a.err = true
+			return
+		}
+		verb := s[i]
+		if verb == '%' {
+			continue
+		}
+		if !strings.ContainsRune("dcfs", rune(verb)) {
+			a.Logger.AddAnalyseErr(token, logger.CodeBadFormat, "无效的格式说明符: ", "%"+string(verb))
+			a.err = true
+			return
+		}
+		if n == len(args) {
+			break
+		}
+		arg := args[n]
+		n++
+		t := consts.TYPESTR
+		if arg.Value != consts.STRING_CONSTANT {
+			t = a.exprType(arg)
+		}
+		if t == "" || formatMatch(verb, t) {
+			continue
+		}
+		a.Logger.AddAnalyseErr(firstToken(arg), logger.CodeFormatType, "格式说明符与实参类型不匹配: ", "%"+string(verb), " -> ", t)
+		a.err = true
+	}
+	if want := strings.Count(strings.ReplaceAll(s, "%%", ""), "%"); want != len(args) {
+		a.Logger.AddAnalyseErr(token, logger.CodeFormatCount, "格式说明符与实参个数不匹配: ", fmt.Sprintf("需要%d个, 实际为%d个", want, len(args)))
+		a.err = true
+	}
+}
+
+// formatMatch 判断格式说明符能否输出该类型的实参
+func formatMatch(verb byte, t string) bool {
+	switch verb {
+	case 'd', 'c':
+		return t == consts.TYPEINT || t == consts.TYPECHAR || t == consts.TYPEBOOL
+	case 'f':
+		return t == consts.TYPEFLOAT
+	}
+	return t == consts.TYPESTR
+}
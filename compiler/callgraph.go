@@ -0,0 +1,238 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/util"
+	"fmt"
+	"strings"
+)
+
+// CallNode 调用图中的一个函数
+type CallNode struct {
+	Name      string   `json:"name"`
+	Builtin   bool     `json:"builtin"`   //内置函数，只作为被调用的函数出现
+	Reachable bool     `json:"reachable"` //从main出发可以调用到
+	Recursive bool     `json:"recursive"` //位于递归调用的环上
+	Called    bool     `json:"called"`    //被其他函数或自身调用过
+	Calls     []string `json:"calls"`     //调用的函数，按第一次调用的顺序排列
+}
+
+// CallGraph 函数调用图，由四元式中的call得到
+type CallGraph struct {
+	Nodes       []*CallNode `json:"functions"`   //用户定义的函数按四元式中的顺序排列，main在最前，之后是被调用的内置函数
+	Cycles      [][]string  `json:"cycles"`      //递归调用形成的强连通分量，包括直接调用自身的函数
+	Uncalled    []string    `json:"uncalled"`    //从未被调用的函数，不包括main
+	Unreachable []string    `json:"unreachable"` //从main出发调用不到的函数
+	index       map[string]*CallNode
+}
+
+// CallGraph 由四元式建立函数调用图
+func (a *Analyser) CallGraph() *CallGraph {
+	g := &CallGraph{index: make(map[string]*CallNode)}
+	quas := a.Qf.QuaForms
+	ranges := a.funcRanges()
+	for _, r := range ranges {
+		g.node(quas[r[0]].Op.(string), false)
+	}
+	if main, ok := g.index["main"]; ok && len(ranges) > 0 { //main之前是全局变量的初始化，在进入main前执行，看作由main调用
+		g.scanCalls(a, main, 0, ranges[0][0])
+	}
+	for _, r := range ranges {
+		g.scanCalls(a, g.index[quas[r[0]].Op.(string)], r[0]+1, r[1])
+	}
+	for _, n := range g.Nodes {
+		for _, callee := range n.Calls {
+			if c, ok := g.index[callee]; ok && callee != n.Name { //递归调用自身不算被调用
+				c.Called = true
+			}
+		}
+	}
+	g.reach("main")
+	g.findCycles()
+	for _, n := range g.Nodes {
+		if n.Builtin {
+			continue
+		}
+		if !n.Called && n.Name != "main" {
+			g.Uncalled = append(g.Uncalled, n.Name)
+		}
+		if !n.Reachable {
+			g.Unreachable = append(g.Unreachable, n.Name)
+		}
+	}
+	return g
+}
+
+// scanCalls 记录第[start, end)条四元式中caller发出的调用
+func (g *CallGraph) scanCalls(a *Analyser, caller *CallNode, start, end int) {
+	quas := a.Qf.QuaForms
+	for i := start; i < end; i++ {
+		if op, _ := quas[i].Op.(string); op != consts.QuaFormMap[consts.QUA_CALL] {
+			continue
+		}
+		callee, ok := quas[i].Arg1.(string)
+		if !ok {
+			continue
+		}
+		f, _ := a.SymbolTable.FindFunction(callee)
+		if _, ok := g.index[callee]; !ok && f != nil && f.builtin {
+			g.node(callee, true)
+		}
+		caller.call(callee)
+	}
+}
+
+// node 返回名为name的函数，不存在时添加
+func (g *CallGraph) node(name string, builtin bool) *CallNode {
+	if n, ok := g.index[name]; ok {
+		return n
+	}
+	n := &CallNode{Name: name, Builtin: builtin, Calls: []string{}}
+	g.index[name] = n
+	g.Nodes = append(g.Nodes, n)
+	return n
+}
+
+// call 记录一条调用边，重复的调用只记录一次
+func (n *CallNode) call(callee string) {
+	for _, c := range n.Calls {
+		if c == callee {
+			return
+		}
+	}
+	n.Calls = append(n.Calls, callee)
+}
+
+// reach 标记从name出发可以调用到的函数
+func (g *CallGraph) reach(name string) {
+	n, ok := g.index[name]
+	if !ok || n.Reachable {
+		return
+	}
+	n.Reachable = true
+	for _, callee := range n.Calls {
+		g.reach(callee)
+	}
+}
+
+// findCycles 用Tarjan算法求强连通分量，含有多个函数或者函数调用自身的分量为递归
+func (g *CallGraph) findCycles() {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var visit func(n *CallNode)
+	visit = func(n *CallNode) {
+		index[n.Name] = len(index)
+		low[n.Name] = index[n.Name]
+		stack = append(stack, n.Name)
+		onStack[n.Name] = true
+		for _, callee := range n.Calls {
+			c, ok := g.index[callee]
+			if !ok {
+				continue
+			}
+			if _, seen := index[callee]; !seen {
+				visit(c)
+				low[n.Name] = min(low[n.Name], low[callee])
+			} else if onStack[callee] {
+				low[n.Name] = min(low[n.Name], index[callee])
+			}
+		}
+		if low[n.Name] != index[n.Name] {
+			return
+		}
+		var scc []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == n.Name {
+				break
+			}
+		}
+		if len(scc) > 1 || g.callsSelf(n) {
+			g.Cycles = append(g.Cycles, g.ordered(scc))
+		}
+	}
+	for _, n := range g.Nodes {
+		if _, seen := index[n.Name]; !seen {
+			visit(n)
+		}
+	}
+	for _, scc := range g.Cycles {
+		for _, name := range scc {
+			g.index[name].Recursive = true
+		}
+	}
+}
+
+// callsSelf 判断函数是否直接调用自身
+func (g *CallGraph) callsSelf(n *CallNode) bool {
+	for _, callee := range n.Calls {
+		if callee == n.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// ordered 按函数在调用图中的顺序排列一组函数名
+func (g *CallGraph) ordered(names []string) []string {
+	in := make(map[string]bool, len(names))
+	for _, name := range names {
+		in[name] = true
+	}
+	var sorted []string
+	for _, n := range g.Nodes {
+		if in[n.Name] {
+			sorted = append(sorted, n.Name)
+		}
+	}
+	return sorted
+}
+
+// DOT 以Graphviz的DOT格式导出调用图，内置函数为方框，递归的函数为红色，从main调用不到的函数为虚线
+func (g *CallGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph calls {\n")
+	for _, n := range g.Nodes {
+		var attrs []string
+		if n.Builtin {
+			attrs = append(attrs, "shape=box")
+		}
+		if n.Recursive {
+			attrs = append(attrs, "color=red")
+		}
+		if !n.Reachable {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) == 0 {
+			fmt.Fprintf(&b, "  %q;\n", n.Name)
+		} else {
+			fmt.Fprintf(&b, "  %q [%s];\n", n.Name, strings.Join(attrs, ", "))
+		}
+	}
+	for _, n := range g.Nodes {
+		for _, callee := range n.Calls {
+			fmt.Fprintf(&b, "  %q -> %q;\n", n.Name, callee)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// JSON 以JSON导出调用图
+func (g *CallGraph) JSON() ([]byte, error) {
+	out := *g
+	for _, list := range []*[]string{&out.Uncalled, &out.Unreachable} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+	if out.Cycles == nil {
+		out.Cycles = [][]string{}
+	}
+	return util.MarshalJSON(out)
+}
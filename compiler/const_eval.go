@@ -0,0 +1,350 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 16位整型的取值范围
+const (
+	minInt16 = math.MinInt16
+	maxInt16 = math.MaxInt16
+)
+
+// ConstValue 编译期求值得到的常量值，整型、字符型和布尔型的值存放在Int中，浮点型的值存放在Float中
+type ConstValue struct {
+	Type  string
+	Int   int
+	Float float64
+	Text  string // 表达式只是一个常数时保留常数的原文
+}
+
+// String 将常量值格式化为四元式中的常数
+func (v ConstValue) String() string {
+	if v.Text != "" {
+		return v.Text
+	}
+	if v.Type == consts.TYPEFLOAT {
+		s := strconv.FormatFloat(v.Float, 'f', -1, 32)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+	return strconv.Itoa(v.Int)
+}
+
+// number 将常量值转换为浮点数
+func (v ConstValue) number() float64 {
+	if v.Type == consts.TYPEFLOAT {
+		return v.Float
+	}
+	return float64(v.Int)
+}
+
+// truth 常量值作为判断条件时的真假
+func (v ConstValue) truth() bool {
+	return v.number() != 0
+}
+
+// convert 将常量值转换为指定的类型，浮点数转换为整型时截断
+func (v ConstValue) convert(t string) ConstValue {
+	switch {
+	case t == "" || t == v.Type:
+		return v
+	case t == consts.TYPEFLOAT:
+		return ConstValue{Type: t, Float: v.number()}
+	case t == consts.TYPEBOOL:
+		return boolValue(v.truth())
+	case v.Type == consts.TYPEFLOAT:
+		return ConstValue{Type: consts.TYPEINT, Int: int(v.Float)}.convert(t)
+	case t == consts.TYPECHAR: //字符型只保留低8位
+		return ConstValue{Type: t, Int: int(int8(v.Int))}
+	}
+	return ConstValue{Type: t, Int: v.Int}
+}
+
+// changes 判断常量值隐式转换为类型t时是否会丢失精度
+func (v ConstValue) changes(t string) bool {
+	return isNarrowing(v.Type, t) && v.convert(t).number() != v.number()
+}
+
+// boolValue 由真假构造bool型的常量值
+func boolValue(b bool) ConstValue {
+	if b {
+		return ConstValue{Type: consts.TYPEBOOL, Int: 1}
+	}
+	return ConstValue{Type: consts.TYPEBOOL, Int: 0}
+}
+
+// ConstErr 常量表达式求值时的错误
+type ConstErr struct {
+	Token *util.TokenNode
+	Msg   string
+	Code  string //诊断码
+}
+
+// evalConst 在编译期求常量表达式的值，表达式中只能出现常数和已声明的常量
+func (a *Analyser) evalConst(node *util.TreeNode) (ConstValue, *ConstErr) {
+	if !isLegalNode(node) {
+		return ConstValue{}, &ConstErr{Token: firstToken(node), Code: logger.CodeConstMissing, Msg: "缺少常量表达式"}
+	}
+	switch node.Value {
+	case consts.BOOLEAN_EXPR:
+		if ternary := node.Children[len(node.Children)-1]; ternary.Value == consts.TERNARY_EXPR && isLegalNode(ternary) {
+			cond, err := a.evalOr(node)
+			if err != nil {
+				return cond, err
+			}
+			// 两个分支都需要是常量表达式，结果转换为两个分支的公共类型
+			v1, err := a.evalConst(ternary.Children[1])
+			if err != nil {
+				return v1, err
+			}
+			v2, err := a.evalConst(ternary.Children[3])
+			if err != nil {
+				return v2, err
+			}
+			t := commonType(v1.Type, v2.Type)
+			if cond.truth() {
+				return v1.convert(t), nil
+			}
+			return v2.convert(t), nil
+		}
+		return a.evalOr(node)
+	case consts.BOOLEAN_ITEM:
+		v, err := a.evalConst(node.Children[0])
+		if err != nil || !isLegalNode(node.Children[1]) {
+			return v, err
+		}
+		b := v.truth()
+		for rest := node.Children[1]; isLegalNode(rest); rest = rest.Children[2] {
+			v, err = a.evalConst(rest.Children[1])
+			if err != nil {
+				return v, err
+			}
+			b = b && v.truth()
+		}
+		return boolValue(b), nil
+	case consts.BOOLEAN_FACTOR:
+		v1, err := a.evalConst(node.Children[0])
+		if err != nil || !isLegalNode(node.Children[1]) {
+			return v1, err
+		}
+		rela := node.Children[1]
+		v2, err := a.evalConst(rela.Children[1])
+		if err != nil {
+			return v2, err
+		}
+		return evalRelation(v1, rela.Children[0].Children[0].Value, v2), nil
+	case consts.ARITHMETIC_EXPR:
+		ops, terms := arithTerms(node)
+		v, err := a.evalConst(terms[0])
+		for i := 1; i < len(terms) && err == nil; i++ {
+			var rhs ConstValue
+			if rhs, err = a.evalConst(terms[i]); err == nil {
+				v, err = evalBinary(v, ops[i], rhs, firstToken(terms[i]))
+			}
+		}
+		return v, err
+	case consts.TERM:
+		v, err := a.evalConst(node.Children[0])
+		for rest := node.Children[1]; isLegalNode(rest) && err == nil; rest = rest.Children[2] {
+			var rhs ConstValue
+			if rhs, err = a.evalConst(rest.Children[1]); err == nil {
+				v, err = evalBinary(v, rest.Children[0].Value, rhs, rest.Children[0].Token)
+			}
+		}
+		return v, err
+	case consts.FACTOR:
+		return a.evalFactor(node)
+	}
+	return ConstValue{}, &ConstErr{Token: firstToken(node), Code: logger.CodeNotConst, Msg: "不是常量表达式"}
+}
+
+// evalOr 求逻辑或运算的值
+func (a *Analyser) evalOr(node *util.TreeNode) (ConstValue, *ConstErr) {
+	v, err := a.evalConst(node.Children[0])
+	if err != nil || !isLegalNode(node.Children[1]) {
+		return v, err
+	}
+	b := v.truth()
+	for rest := node.Children[1]; isLegalNode(rest); rest = rest.Children[2] {
+		v, err = a.evalConst(rest.Children[1])
+		if err != nil {
+			return v, err
+		}
+		b = b || v.truth()
+	}
+	return boolValue(b), nil
+}
+
+// evalFactor 求因子的值
+func (a *Analyser) evalFactor(node *util.TreeNode) (ConstValue, *ConstErr) {
+	child := node.Children[0]
+	switch child.Value {
+	case "(":
+		return a.evalConst(node.Children[1])
+	case consts.CONSTANT:
+		return literalValue(child.Children[0].Children[0])
+	case consts.VARIABLE:
+		name := child.Children[0]
+		if isLegalNode(memberAccessOf(node, 0)) {
+			return ConstValue{}, &ConstErr{Token: name.Token, Code: logger.CodeConstField, Msg: "常量表达式中不能访问结构体成员: " + name.Value}
+		}
+		info, ok := a.SymbolTable.FindConstant(a.Scope, name.Value)
+		if !ok || a.varIsExist(name.Value) {
+			return ConstValue{}, &ConstErr{Token: name.Token, Code: logger.CodeConstOperand, Msg: "常量表达式中只能使用常量: " + name.Value}
+		}
+		a.Xref.add(info, name.Token)
+		return parseConstValue(a.SymbolTable.underlyingType(info.Type), fmt.Sprint(info.Value), name.Token)
+	case consts.FUNCTION_CALL:
+		return ConstValue{}, &ConstErr{Token: firstToken(child), Code: logger.CodeConstCall, Msg: "常量表达式中不能调用函数"}
+	case consts.FACTOR_0:
+		op := child.Children[0]
+		switch op.Value {
+		case "(":
+			v, err := a.evalConst(child.Children[3])
+			if err != nil {
+				return v, err
+			}
+			t := varTypeOf(child.Children[1])
+			if _, ok := typeRank[t]; !ok {
+				return v, &ConstErr{Token: op.Token, Code: logger.CodeConstConversion, Msg: "常量表达式中不能转换为类型: " + t}
+			}
+			return checkRange(v.convert(t), op.Token)
+		case "*", "&":
+			return ConstValue{}, &ConstErr{Token: op.Token, Code: logger.CodeConstPointer, Msg: "常量表达式中不能使用指针运算"}
+		}
+		v, err := a.evalConst(child.Children[1])
+		if err != nil {
+			return v, err
+		}
+		switch op.Value {
+		case "!":
+			return boolValue(!v.truth()), nil
+		case "-":
+			if v.Type == consts.TYPEFLOAT {
+				return ConstValue{Type: v.Type, Float: -v.Float}, nil
+			}
+			return checkRange(ConstValue{Type: promoteInt(v.Type), Int: -v.Int}, op.Token)
+		}
+		v.Text = ""
+		return v, nil
+	}
+	return ConstValue{}, &ConstErr{Token: firstToken(node), Code: logger.CodeNotConst, Msg: "不是常量表达式"}
+}
+
+// literalValue 求常数的值，字符常数取其ASCII码
+func literalValue(node *util.TreeNode) (ConstValue, *ConstErr) {
+	v, err := parseConstValue(literalType(node.Token), constValue(node), node.Token)
+	if err == nil {
+		v.Text = constValue(node)
+	}
+	return v, err
+}
+
+// parseConstValue 将常数或常量表中的值解析为常量值
+func parseConstValue(t, s string, token *util.TokenNode) (ConstValue, *ConstErr) {
+	switch t {
+	case consts.TYPEFLOAT:
+		f, err := strconv.ParseFloat(s, 32)
+		if err == nil {
+			return ConstValue{Type: t, Float: f}, nil
+		}
+	case consts.TYPECHAR:
+		if r, err := strconv.Unquote(s); err == nil && len(r) == 1 {
+			return ConstValue{Type: t, Int: int(r[0])}, nil
+		}
+		if i, err := strconv.Atoi(s); err == nil {
+			return ConstValue{Type: t, Int: i}, nil
+		}
+		if len(s) == 3 && s[0] == '\'' && s[2] == '\'' {
+			return ConstValue{Type: t, Int: int(s[1])}, nil
+		}
+	case consts.TYPEINT, consts.TYPEBOOL:
+		i, err := strconv.Atoi(s)
+		if err == nil {
+			return checkRange(ConstValue{Type: t, Int: i}, token)
+		}
+	}
+	return ConstValue{}, &ConstErr{Token: token, Code: logger.CodeNotConst, Msg: "不是常量表达式"}
+}
+
+// promoteInt 字符型和布尔型参与运算时提升为整型
+func promoteInt(t string) string {
+	if t == consts.TYPEFLOAT {
+		return t
+	}
+	return consts.TYPEINT
+}
+
+// checkRange 检查整型的值是否超出16位的范围
+func checkRange(v ConstValue, token *util.TokenNode) (ConstValue, *ConstErr) {
+	if v.Type != consts.TYPEFLOAT && (v.Int < minInt16 || v.Int > maxInt16) {
+		return v, &ConstErr{Token: token, Code: logger.CodeConstOverflow, Msg: "常量表达式溢出: " + strconv.Itoa(v.Int)}
+	}
+	return v, nil
+}
+
+// evalBinary 求算术运算的值，整型的除法和取模与C语言一致向零截断
+func evalBinary(v1 ConstValue, op string, v2 ConstValue, token *util.TokenNode) (ConstValue, *ConstErr) {
+	t := promoteInt(commonType(promoteInt(v1.Type), promoteInt(v2.Type)))
+	if (op == "/" || op == "%") && !v2.truth() {
+		return ConstValue{}, &ConstErr{Token: token, Code: logger.CodeConstDivByZero, Msg: "除数不能为0"}
+	}
+	if t == consts.TYPEFLOAT {
+		if op == "%" {
+			return ConstValue{}, &ConstErr{Token: token, Code: logger.CodeConstFloatMod, Msg: "浮点数不能取模"}
+		}
+		x, y := v1.number(), v2.number()
+		switch op {
+		case "+":
+			x += y
+		case "-":
+			x -= y
+		case "*":
+			x *= y
+		case "/":
+			x /= y
+		}
+		return ConstValue{Type: t, Float: x}, nil
+	}
+	x, y := v1.Int, v2.Int
+	switch op {
+	case "+":
+		x += y
+	case "-":
+		x -= y
+	case "*":
+		x *= y
+	case "/":
+		x /= y
+	case "%":
+		x %= y
+	}
+	return checkRange(ConstValue{Type: t, Int: x}, token)
+}
+
+// evalRelation 求关系运算的值
+func evalRelation(v1 ConstValue, op string, v2 ConstValue) ConstValue {
+	x, y := v1.number(), v2.number()
+	switch op {
+	case ">":
+		return boolValue(x > y)
+	case ">=":
+		return boolValue(x >= y)
+	case "<":
+		return boolValue(x < y)
+	case "<=":
+		return boolValue(x <= y)
+	case "==":
+		return boolValue(x == y)
+	}
+	return boolValue(x != y)
+}
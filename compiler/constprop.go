@@ -0,0 +1,239 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+	"fmt"
+	"strconv"
+)
+
+// constVal 常量传播中变量的值，known为false表示不是常量，没有赋值的变量不在constEnv中
+type constVal struct {
+	known bool
+	value int
+}
+
+// constEnv 程序某一点上变量的值
+type constEnv map[string]constVal
+
+func (e constEnv) copy() constEnv {
+	c := make(constEnv, len(e))
+	for k, v := range e {
+		c[k] = v
+	}
+	return c
+}
+
+func (e constEnv) equal(f constEnv) bool {
+	if len(e) != len(f) {
+		return false
+	}
+	for k, v := range e {
+		if w, ok := f[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// meet 合并另一个前驱的值，两边的值不同时不是常量
+func (e constEnv) meet(f constEnv) {
+	for k, v := range f {
+		if w, ok := e[k]; !ok {
+			e[k] = v
+		} else if w != v {
+			e[k] = constVal{}
+		}
+	}
+}
+
+// constProp 一个函数的常量传播
+type constProp struct {
+	g       *flowGraph
+	entry   constEnv        //函数入口处的值，只有常量
+	globals map[string]bool //全局变量，调用函数或通过指针赋值后不再是常量
+	escaped map[string]bool //取过地址的变量，始终不是常量
+}
+
+// checkConstArith 对每个函数的四元式做常量传播，报告除数一定为0的除法、取模，以及结果超出16位整数范围的整型运算
+func (a *Analyser) checkConstArith() {
+	globals := make(map[string]bool)
+	for name := range a.SymbolTable.VarTable[consts.ALL] {
+		globals[name] = true
+	}
+	for _, r := range a.funcRanges() {
+		start, end := r[0], r[1]
+		p := &constProp{
+			g:       newFlowGraph(a.Qf.QuaForms, start, end),
+			entry:   a.constEntry(a.Qf.QuaForms[start].Op.(string)),
+			globals: globals,
+			escaped: make(map[string]bool),
+		}
+		for i := start; i < end; i++ {
+			if op, _ := a.Qf.QuaForms[i].Op.(string); op == consts.QuaFormMap[consts.QUA_ADDR] {
+				if name, ok := a.Qf.QuaForms[i].Arg1.(string); ok {
+					p.escaped[name] = true
+				}
+			}
+		}
+		in := p.solve()
+		for b, block := range p.g.blocks {
+			if in[b] == nil { //不可达
+				continue
+			}
+			env := in[b].copy()
+			for i := block.start; i < block.end; i++ {
+				a.checkQuaArith(p, env, p.g.quas[i])
+				p.transfer(env, p.g.quas[i])
+			}
+		}
+	}
+}
+
+// constEntry 函数入口处已知的值: 全局和函数中的整型常量，被同名局部变量遮蔽的除外
+func (a *Analyser) constEntry(scope string) constEnv {
+	env := constEnv{}
+	for _, table := range []map[string]*Info{a.SymbolTable.ConstTable[consts.ALL], a.SymbolTable.ConstTable[scope]} {
+		for name, c := range table {
+			if s, ok := c.Value.(string); ok {
+				if n, err := strconv.Atoi(s); err == nil {
+					env[name] = constVal{true, n}
+				}
+			}
+		}
+	}
+	for name := range a.SymbolTable.VarTable[scope] {
+		delete(env, name)
+	}
+	return env
+}
+
+// solve 求每个基本块入口处的值，不可达的基本块为nil
+func (p *constProp) solve() []constEnv {
+	in := make([]constEnv, len(p.g.blocks))
+	out := make([]constEnv, len(p.g.blocks))
+	for changed := true; changed; {
+		changed = false
+		for b, block := range p.g.blocks {
+			var env constEnv
+			if b == 0 {
+				env = p.entry.copy()
+			}
+			for _, pred := range block.preds {
+				if out[pred] == nil {
+					continue
+				}
+				if env == nil {
+					env = out[pred].copy()
+				} else {
+					env.meet(out[pred])
+				}
+			}
+			if env == nil {
+				continue
+			}
+			in[b] = env
+			env = env.copy()
+			for i := block.start; i < block.end; i++ {
+				p.transfer(env, p.g.quas[i])
+			}
+			if out[b] == nil || !env.equal(out[b]) {
+				out[b] = env
+				changed = true
+			}
+		}
+	}
+	return in
+}
+
+// value 求操作数的值，操作数为整数常数或者值已知的变量时返回true
+func (p *constProp) value(env constEnv, arg any) (int, bool) {
+	switch v := arg.(type) {
+	case int:
+		return v, true
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n, true
+		}
+		if c, ok := env[v]; ok && c.known && !p.escaped[v] {
+			return c.value, true
+		}
+	}
+	return 0, false
+}
+
+// transfer 执行一条四元式后变量的值
+func (p *constProp) transfer(env constEnv, q *util.QuaForm) {
+	op, _ := q.Op.(string)
+	result, _ := q.Result.(string)
+	switch op {
+	case consts.QuaFormMap[consts.QUA_ASSIGNMENT]:
+		v, ok := p.value(env, q.Arg1)
+		env[result] = constVal{ok, v}
+		return
+	case consts.QuaFormMap[consts.QUA_ADD], consts.QuaFormMap[consts.QUA_SUB], consts.QuaFormMap[consts.QUA_MUL],
+		consts.QuaFormMap[consts.QUA_DIV], consts.QuaFormMap[consts.QUA_MOD]:
+		x, ok1 := p.value(env, q.Arg1)
+		y, ok2 := p.value(env, q.Arg2)
+		if v, ok := foldInt(op, x, y); ok1 && ok2 && ok {
+			env[result] = constVal{true, int(int16(v))} //与运行时一样只保留低16位
+			return
+		}
+	case consts.QuaFormMap[consts.QUA_NEGATIVE]:
+		if v, ok := p.value(env, q.Arg1); ok {
+			env[result] = constVal{true, int(int16(-v))}
+			return
+		}
+	case consts.QuaFormMap[consts.QUA_CALL], consts.QuaFormMap[consts.QUA_DEREFSET]:
+		for name := range p.globals {
+			env[name] = constVal{}
+		}
+	case consts.QuaFormMap[consts.QUA_RETURN], consts.QuaFormMap[consts.QUA_SYS]:
+		return
+	}
+	if result != "" {
+		env[result] = constVal{}
+	}
+}
+
+// checkQuaArith 检查一条算术运算四元式，env为执行该四元式之前变量的值
+func (a *Analyser) checkQuaArith(p *constProp, env constEnv, q *util.QuaForm) {
+	if q.Token == nil {
+		return
+	}
+	op, _ := q.Op.(string)
+	x, ok1 := p.value(env, q.Arg1)
+	y, ok2 := p.value(env, q.Arg2)
+	switch op {
+	case consts.QuaFormMap[consts.QUA_DIV], consts.QuaFormMap[consts.QUA_MOD]:
+		if ok2 && y == 0 {
+			a.warn(WarnDivByZero, q.Token, logger.CodeDivByZero, "除数为0")
+		}
+	case consts.QuaFormMap[consts.QUA_ADD], consts.QuaFormMap[consts.QUA_SUB], consts.QuaFormMap[consts.QUA_MUL]:
+		if v, _ := foldInt(op, x, y); ok1 && ok2 && (v < minInt16 || v > maxInt16) {
+			a.warn(WarnOverflow, q.Token, logger.CodeOverflow, "整数运算溢出: ", fmt.Sprintf("%d %s %d = %d, 超出16位整数的范围", x, op, y, v))
+		}
+	}
+}
+
+// foldInt 求整型算术运算的值，除法和取模向零截断，除数为0时返回false
+func foldInt(op string, x, y int) (int, bool) {
+	switch op {
+	case "+":
+		return x + y, true
+	case "-":
+		return x - y, true
+	case "*":
+		return x * y, true
+	case "/", "%":
+		if y == 0 {
+			return 0, false
+		}
+		if op == "/" {
+			return x / y, true
+		}
+		return x % y, true
+	}
+	return 0, false
+}
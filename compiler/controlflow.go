@@ -0,0 +1,153 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+)
+
+// flowChecker 在语法树上检查语句的可达性
+type flowChecker struct {
+	a     *Analyser
+	loops []*loopFlow //外层到内层的循环
+	dead  bool        //已经报告过当前这段不可达的语句
+}
+
+// loopFlow 循环体中是否有跳出或继续该循环的语句
+type loopFlow struct {
+	broken    bool
+	continued bool
+}
+
+// checkFlow 检查函数体中不可达的语句，返回函数体的结尾是否可达，即是否有路径没有经过return语句
+func (a *Analyser) checkFlow(body *util.TreeNode) bool {
+	c := &flowChecker{a: a}
+	return c.flow(body, true)
+}
+
+// flow 求执行完node之后是否可达，reachable为执行node之前是否可达。
+// 不可达的语句只报告每一段中的第一条，且不再检查其内部
+func (c *flowChecker) flow(node *util.TreeNode, reachable bool) bool {
+	if !isLegalNode(node) {
+		return reachable
+	}
+	switch node.Value {
+	case consts.EXECUTION_STMT:
+		if !reachable {
+			if !c.dead {
+				c.a.warn(WarnUnreachableCode, firstToken(node), logger.CodeUnreachable, "语句不可达")
+				c.dead = true
+			}
+			return false
+		}
+		c.dead = false
+		return c.flow(node.Children[0], true)
+	case consts.COMPOUND_STMT, consts.STATEMENT_TABLE, consts.STATEMENT_TABLE_0, consts.STATEMENT, consts.CONTROL_STMT, consts.IF_TAIL, consts.IF_TAIL_0:
+		for _, child := range node.Children {
+			reachable = c.flow(child, reachable)
+		}
+		return reachable
+	case consts.IF_STMT:
+		cond := c.condition(childOf(node, consts.BOOLEAN_EXPR))
+		then := c.flow(childOf(node, consts.COMPOUND_STMT), reachable)
+		els := reachable
+		if tail := childOf(node, consts.IF_TAIL); isLegalNode(tail) {
+			els = c.flow(tail, reachable)
+		}
+		switch cond {
+		case condTrue:
+			return then
+		case condFalse:
+			return els
+		}
+		return then || els
+	case consts.WHILE_STMT, consts.FOR_STMT:
+		cond := c.condition(childOf(node, consts.BOOLEAN_EXPR))
+		loop := c.loop(childOf(node, consts.COMPOUND_STMT), reachable)
+		return reachable && (cond != condTrue || loop.broken)
+	case consts.DO_WHILE_STMT:
+		cond := c.condition(childOf(node, consts.BOOLEAN_EXPR))
+		loop := &loopFlow{}
+		end := false
+		if reachable {
+			c.loops = append(c.loops, loop)
+			end = c.flow(childOf(node, consts.COMPOUND_STMT), true) || loop.continued
+			c.loops = c.loops[:len(c.loops)-1]
+		}
+		return end && cond != condTrue || loop.broken
+	case consts.RETURN_STMT:
+		return false
+	case consts.BREAK_STMT:
+		n := len(c.loops)
+		if n == 0 { //不在循环中的break已经报错，不影响后面语句的可达性
+			return reachable
+		}
+		c.loops[n-1].broken = true
+		return false
+	case consts.CONTINUE_STMT:
+		n := len(c.loops)
+		if n == 0 {
+			return reachable
+		}
+		c.loops[n-1].continued = true
+		return false
+	}
+	return reachable
+}
+
+// loop 检查循环体，循环不可达时不检查
+func (c *flowChecker) loop(body *util.TreeNode, reachable bool) *loopFlow {
+	loop := &loopFlow{}
+	if reachable {
+		c.loops = append(c.loops, loop)
+		c.flow(body, true)
+		c.loops = c.loops[:len(c.loops)-1]
+	}
+	return loop
+}
+
+// 判断条件的值
+const (
+	condUnknown = iota // 不是常量表达式
+	condTrue           // 恒为true
+	condFalse          // 恒为false
+)
+
+// condition 求判断条件是否为常量
+func (c *flowChecker) condition(node *util.TreeNode) int {
+	v, err := c.a.evalConst(node)
+	switch {
+	case err != nil:
+		return condUnknown
+	case v.truth():
+		return condTrue
+	}
+	return condFalse
+}
+
+// childOf 返回节点中第一个名为value的子节点
+func childOf(node *util.TreeNode, value string) *util.TreeNode {
+	for _, child := range node.Children {
+		if child.Value == value {
+			return child
+		}
+	}
+	return nil
+}
+
+// checkReturn 检查return语句与函数的返回类型是否相符，void函数不能返回值，其他函数必须返回值
+func (a *Analyser) checkReturn(token *util.TokenNode, ret0 *util.TreeNode) {
+	f, ok := a.SymbolTable.FindFunction(a.currentFunc)
+	if !ok || f.builtin || !isLegalNode(ret0) { //重新定义内置函数时已经报错
+		return
+	}
+	value := ret0.Children[0].Value != ";"
+	switch {
+	case f.Type == consts.TYPEVOID && value:
+		a.Logger.AddAnalyseErr(token, logger.CodeVoidReturnValue, "void函数不能返回值: ", a.currentFunc)
+		a.err = true
+	case f.Type != consts.TYPEVOID && !value:
+		a.Logger.AddAnalyseErr(token, logger.CodeMissingReturnValue, "函数缺少返回值: ", a.currentFunc)
+		a.err = true
+	}
+}
@@ -0,0 +1,272 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+	"sort"
+)
+
+// varRead 表达式中对变量的一次读取，index为读取时已经生成的四元式个数
+type varRead struct {
+	info  *Info
+	token *util.TokenNode
+	index int
+}
+
+// recordRead 记录对变量的读取，分析结束后检查读取前变量是否已经赋值
+func (a *Analyser) recordRead(node *util.TreeNode) {
+	if info, ok := a.SymbolTable.FindVariable(a.Scope, node.Value); ok && info.Scope != consts.ALL {
+		a.reads = append(a.reads, varRead{info, node.Token, len(a.Qf.QuaForms)})
+	}
+}
+
+// flowBlock 控制流图中的基本块，四元式范围为[start, end)
+type flowBlock struct {
+	start, end int
+	succs      []int
+	preds      []int
+}
+
+// flowGraph 一个函数的四元式构成的控制流图
+type flowGraph struct {
+	quas    []*util.QuaForm
+	blocks  []*flowBlock
+	blockOf map[int]int //基本块的首条四元式->基本块下标
+}
+
+// newFlowGraph 将函数的四元式[start, end)划分为基本块，跳转的目标和跳转的下一条四元式为基本块的入口，
+// 无条件跳转、ret和sys之后的四元式不是前一条四元式的后继
+func newFlowGraph(quas []*util.QuaForm, start, end int) *flowGraph {
+	leaders := map[int]bool{start: true}
+	for i := start; i < end; i++ {
+		op, _ := quas[i].Op.(string)
+		if target, ok := quas[i].Result.(int); ok && isTransferStatement(op) {
+			if target >= start && target < end {
+				leaders[target] = true
+			}
+			leaders[i+1] = true
+		} else if op == consts.QuaFormMap[consts.QUA_RETURN] || op == consts.QuaFormMap[consts.QUA_SYS] {
+			leaders[i+1] = true
+		}
+	}
+	var starts []int
+	for i := range leaders {
+		if i < end {
+			starts = append(starts, i)
+		}
+	}
+	sort.Ints(starts)
+
+	g := &flowGraph{quas: quas, blockOf: make(map[int]int)}
+	for i, s := range starts {
+		e := end
+		if i+1 < len(starts) {
+			e = starts[i+1]
+		}
+		g.blockOf[s] = len(g.blocks)
+		g.blocks = append(g.blocks, &flowBlock{start: s, end: e})
+	}
+	for i, b := range g.blocks {
+		last := quas[b.end-1]
+		op, _ := last.Op.(string)
+		falls := true
+		switch {
+		case op == consts.QuaFormMap[consts.QUA_RETURN] || op == consts.QuaFormMap[consts.QUA_SYS]:
+			falls = false
+		case isTransferStatement(op):
+			if target, ok := last.Result.(int); ok {
+				if t, ok := g.blockOf[target]; ok {
+					g.addEdge(i, t)
+				}
+			}
+			falls = op != consts.QuaFormMap[consts.QUA_JMP]
+		}
+		if falls && i+1 < len(g.blocks) {
+			g.addEdge(i, i+1)
+		}
+	}
+	return g
+}
+
+func (g *flowGraph) addEdge(from, to int) {
+	g.blocks[from].succs = append(g.blocks[from].succs, to)
+	g.blocks[to].preds = append(g.blocks[to].preds, from)
+}
+
+// varSet 变量集合，以变量在变量表中的名字为元素
+type varSet map[string]bool
+
+func (s varSet) copy() varSet {
+	c := make(varSet, len(s))
+	for k := range s {
+		c[k] = true
+	}
+	return c
+}
+
+func (s varSet) equal(t varSet) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for k := range s {
+		if !t[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// assignedVars 前向数据流分析，求每个基本块入口处已经赋值的变量。must为true时求在所有路径上都已赋值的变量(交)，
+// 否则求在某条路径上已经赋值的变量(并)。不可达的基本块在must分析中取全集
+func (g *flowGraph) assignedVars(vars varSet, must bool) []varSet {
+	in := make([]varSet, len(g.blocks))
+	out := make([]varSet, len(g.blocks))
+	for i := range g.blocks {
+		if must && i != 0 {
+			out[i] = vars.copy()
+		} else {
+			out[i] = varSet{}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, b := range g.blocks {
+			var set varSet
+			switch {
+			case i == 0:
+				set = varSet{}
+			case len(b.preds) == 0:
+				set = varSet{}
+				if must {
+					set = vars.copy()
+				}
+			default:
+				set = meet(out, b.preds, must)
+			}
+			in[i] = set
+			set = set.copy()
+			for j := b.start; j < b.end; j++ {
+				g.transfer(j, vars, set)
+			}
+			if !set.equal(out[i]) {
+				out[i] = set
+				changed = true
+			}
+		}
+	}
+	return in
+}
+
+// meet 合并前驱基本块出口处的集合
+func meet(out []varSet, preds []int, must bool) varSet {
+	set := out[preds[0]].copy()
+	for _, p := range preds[1:] {
+		if must {
+			for k := range set {
+				if !out[p][k] {
+					delete(set, k)
+				}
+			}
+		} else {
+			for k := range out[p] {
+				set[k] = true
+			}
+		}
+	}
+	return set
+}
+
+// transfer 第i条四元式对已赋值变量集合的影响，结果为变量的四元式为变量赋值
+func (g *flowGraph) transfer(i int, vars varSet, set varSet) {
+	if name, ok := g.quas[i].Result.(string); ok && vars[name] {
+		set[name] = true
+	}
+}
+
+// checkUninitialized 对每个函数的四元式做确定赋值分析，报告读取时可能还没有赋值的局部变量。
+// 形参、结构体变量和取过地址的变量不检查
+func (a *Analyser) checkUninitialized() {
+	quas := a.Qf.QuaForms
+	reported := make(map[*Info]bool)
+	for _, r := range a.funcRanges() {
+		start, end := r[0], r[1]
+		scope := quas[start].Op.(string)
+		vars := a.checkedVars(scope, start, end)
+		if len(vars) == 0 {
+			continue
+		}
+		g := newFlowGraph(quas, start, end)
+		must, may := g.assignedVars(vars, true), g.assignedVars(vars, false)
+		for _, r := range a.reads {
+			if r.info.Scope != scope || !vars[r.info.Name] || reported[r.info] || r.index < start || r.index >= end {
+				continue
+			}
+			i := r.index
+			b := g.blockAt(i)
+			mustSet, maySet := must[b].copy(), may[b].copy()
+			for j := g.blocks[b].start; j < i; j++ {
+				g.transfer(j, vars, mustSet)
+				g.transfer(j, vars, maySet)
+			}
+			if mustSet[r.info.Name] {
+				continue
+			}
+			reported[r.info] = true
+			if maySet[r.info.Name] {
+				a.warn(WarnMaybeUninitialized, r.token, logger.CodeMaybeUninitialized, "变量可能未初始化: ", sourceName(r.info.Name)).WithRelated(r.info.token, "变量声明位于此处")
+			} else {
+				a.warn(WarnUninitialized, r.token, logger.CodeUninitialized, "变量未初始化: ", sourceName(r.info.Name)).WithRelated(r.info.token, "变量声明位于此处")
+			}
+		}
+	}
+}
+
+// funcRanges 每个函数的四元式范围[start, end)，第一条四元式的op为函数名
+func (a *Analyser) funcRanges() [][2]int {
+	quas := a.Qf.QuaForms
+	var starts []int
+	for i, q := range quas {
+		if op, ok := q.Op.(string); ok {
+			if f, ok := a.SymbolTable.FindFunction(op); ok && !f.builtin {
+				starts = append(starts, i)
+			}
+		}
+	}
+	ranges := make([][2]int, len(starts))
+	for n, start := range starts {
+		end := len(quas)
+		if n+1 < len(starts) {
+			end = starts[n+1]
+		}
+		ranges[n] = [2]int{start, end}
+	}
+	return ranges
+}
+
+// blockAt 求第i条四元式所在的基本块
+func (g *flowGraph) blockAt(i int) int {
+	return sort.Search(len(g.blocks), func(b int) bool { return g.blocks[b].end > i })
+}
+
+// checkedVars 函数中需要检查的局部变量，取过地址的变量可能通过指针赋值，不检查
+func (a *Analyser) checkedVars(scope string, start, end int) varSet {
+	vars := varSet{}
+	for name, v := range a.SymbolTable.VarTable[scope] {
+		if v.ParamFlag || v.token == nil {
+			continue
+		}
+		if _, ok := a.SymbolTable.FindType(v.Type); ok {
+			continue
+		}
+		vars[name] = true
+	}
+	for i := start; i < end; i++ {
+		if op, _ := a.Qf.QuaForms[i].Op.(string); op == consts.QuaFormMap[consts.QUA_ADDR] {
+			if name, ok := a.Qf.QuaForms[i].Arg1.(string); ok {
+				delete(vars, name)
+			}
+		}
+	}
+	return vars
+}
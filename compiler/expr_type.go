@@ -0,0 +1,482 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+	"strings"
+)
+
+// typeRank 数值类型的提升等级，等级高的类型可以容纳等级低的类型
+var typeRank = map[string]int{
+	consts.TYPEBOOL:  0,
+	consts.TYPECHAR:  1,
+	consts.TYPEINT:   2,
+	consts.TYPEFLOAT: 3,
+}
+
+// commonType 求两个类型的公共类型，任意一个类型未知时返回另一个类型，两个类型不兼容时返回空串
+func commonType(t1, t2 string) string {
+	if t1 == "" {
+		return t2
+	}
+	if t2 == "" || t1 == t2 {
+		return t1
+	}
+	r1, ok1 := typeRank[t1]
+	r2, ok2 := typeRank[t2]
+	if !ok1 || !ok2 {
+		return ""
+	}
+	if r1 > r2 {
+		return t1
+	}
+	return t2
+}
+
+// literalType 根据常数token的种别码返回常数的类型
+func literalType(token *util.TokenNode) string {
+	if token == nil {
+		return ""
+	}
+	switch token.Type {
+	case consts.TokenMap["integer"]:
+		return consts.TYPEINT
+	case consts.TokenMap["floatnumber"]:
+		return consts.TYPEFLOAT
+	case consts.TokenMap["character"]:
+		return consts.TYPECHAR
+	case consts.TokenMap["true"], consts.TokenMap["false"]:
+		return consts.TYPEBOOL
+	}
+	return ""
+}
+
+// constValue 取出常数的值，布尔常量true和false分别取1和0
+func constValue(node *util.TreeNode) string {
+	switch node.Value {
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	}
+	return node.Value
+}
+
+// firstToken 返回语法树中第一个终结符的token，用于定位错误
+func firstToken(node *util.TreeNode) *util.TokenNode {
+	if node == nil {
+		return nil
+	}
+	if node.Token != nil {
+		return node.Token
+	}
+	for _, child := range node.Children {
+		if token := firstToken(child); token != nil {
+			return token
+		}
+	}
+	return nil
+}
+
+// checkCondition 检查if、while、for语句的判断条件，条件必须为bool类型或者可以隐式转换为bool的数值类型和指针
+func (a *Analyser) checkCondition(node *util.TreeNode) {
+	switch t := a.exprType(node); {
+	case t == "", t == consts.TYPEBOOL, t == consts.TYPEINT, t == consts.TYPECHAR, t == consts.TYPEFLOAT, isPointer(t):
+	default:
+		a.Logger.AddAnalyseErr(firstToken(node), logger.CodeCondType, "判断条件的类型不能转换为bool: ", t)
+		a.err = true
+	}
+}
+
+// symbolType 查找变量或常量的类型
+func (a *Analyser) symbolType(name string) string {
+	if info, ok := a.SymbolTable.FindVariable(a.Scope, name); ok {
+		return info.Type
+	}
+	if info, ok := a.SymbolTable.FindConstant(a.Scope, name); ok {
+		return info.Type
+	}
+	return ""
+}
+
+// exprType 根据语法树推导表达式的类型，无法推导时返回空串
+func (a *Analyser) exprType(node *util.TreeNode) string {
+	if !isLegalNode(node) {
+		return ""
+	}
+	switch node.Value {
+	case consts.BOOLEAN_EXPR:
+		// 带有三目运算时，结果为两个分支的公共类型
+		if ternary := node.Children[len(node.Children)-1]; ternary.Value == consts.TERNARY_EXPR && isLegalNode(ternary) {
+			return commonType(a.exprType(ternary.Children[1]), a.exprType(ternary.Children[3]))
+		}
+		if isLegalNode(node.Children[1]) { // 逻辑或运算的结果为bool
+			return consts.TYPEBOOL
+		}
+		return a.exprType(node.Children[0])
+	case consts.BOOLEAN_ITEM, consts.BOOLEAN_FACTOR:
+		if len(node.Children) > 1 && isLegalNode(node.Children[1]) { // 逻辑与、关系运算的结果为bool
+			return consts.TYPEBOOL
+		}
+		return a.exprType(node.Children[0])
+	case consts.ARITHMETIC_EXPR:
+		ops, terms := arithTerms(node)
+		t := a.exprType(terms[0])
+		for i := 1; i < len(terms); i++ {
+			t = arithType(t, ops[i], a.exprType(terms[i]))
+		}
+		return t
+	case consts.TERM, consts.TERM_0:
+		t := ""
+		for _, child := range node.Children {
+			if child.Value == "%" { // 取模运算的结果为int
+				return consts.TYPEINT
+			}
+			if child.Value == consts.FACTOR || child.Value == consts.TERM_0 {
+				t = commonType(t, a.exprType(child))
+			}
+		}
+		return t
+	case consts.FACTOR:
+		child := node.Children[0]
+		switch child.Value {
+		case "(":
+			return a.exprType(node.Children[1])
+		case consts.CONSTANT:
+			return literalType(child.Children[0].Children[0].Token)
+		case consts.VARIABLE:
+			t, _, errToken := a.resolveField(a.symbolType(child.Children[0].Value), memberAccessOf(node, 0))
+			if errToken != nil {
+				return ""
+			}
+			return a.SymbolTable.underlyingType(t)
+		case consts.FUNCTION_CALL:
+			if info, ok := a.SymbolTable.FindFunction(child.Children[0].Children[0].Value); ok {
+				return info.Type
+			}
+		case consts.FACTOR_0:
+			switch child.Children[0].Value {
+			case "!":
+				return consts.TYPEBOOL
+			case "*":
+				if t := a.exprType(child.Children[1]); isPointer(t) {
+					return elemType(t)
+				}
+				return ""
+			case "(":
+				return varTypeOf(child.Children[1])
+			case "&":
+				t, _, errToken := a.resolveField(a.symbolType(child.Children[1].Children[0].Value), memberAccessOf(child, 1))
+				if errToken != nil || t == "" {
+					return ""
+				}
+				return t + "*"
+			}
+			return a.exprType(child.Children[1])
+		}
+	}
+	return ""
+}
+
+// resolveField 沿成员访问链查找结构体成员，返回最终成员的类型及其相对结构体变量起始地址的偏移，
+// 成员不存在时返回出错成员的token
+func (a *Analyser) resolveField(t string, access *util.TreeNode) (string, int, *util.TokenNode) {
+	offset := 0
+	for isLegalNode(access) {
+		name := access.Children[1].Children[0]
+		info, ok := a.SymbolTable.FindType(t)
+		if !ok {
+			return t, offset, name.Token
+		}
+		field, ok := info.FindField(name.Value)
+		if !ok {
+			return t, offset, name.Token
+		}
+		a.Xref.add(field, name.Token)
+		t = field.Type
+		offset += field.Offset
+		access = access.Children[2]
+	}
+	return t, offset, nil
+}
+
+// isPointer 判断类型是否为指针类型
+func isPointer(t string) bool {
+	return strings.HasSuffix(t, "*")
+}
+
+// elemType 求指针所指的类型
+func elemType(t string) string {
+	return strings.TrimSuffix(t, "*")
+}
+
+// isIntegral 判断类型是否为可以参与指针运算的整型
+func isIntegral(t string) bool {
+	return t == consts.TYPEINT || t == consts.TYPECHAR || t == consts.TYPEBOOL
+}
+
+// varTypeOf 根据<变量类型>节点求出变量的类型，每一级指针在类型后加一个*
+func varTypeOf(node *util.TreeNode) string {
+	t := node.Children[0].Value
+	if len(node.Children) < 2 {
+		return t
+	}
+	for p := node.Children[1]; isLegalNode(p); p = p.Children[1] {
+		t += "*"
+	}
+	return t
+}
+
+// arithType 求加减运算结果的类型，指针加减整数结果仍为指针，其余不合法的指针运算返回空串
+func arithType(t1, op, t2 string) string {
+	switch {
+	case isPointer(t1) && isPointer(t2):
+		return ""
+	case isPointer(t1):
+		if isIntegral(t2) {
+			return t1
+		}
+		return ""
+	case isPointer(t2):
+		if op == "+" && isIntegral(t1) {
+			return t2
+		}
+		return ""
+	}
+	return commonType(t1, t2)
+}
+
+// arithTerms 将算术表达式按从左到右的顺序展开为项的列表，ops[i]为第i项前面的运算符
+func arithTerms(node *util.TreeNode) (ops []string, terms []*util.TreeNode) {
+	ops = append(ops, "")
+	terms = append(terms, node.Children[0])
+	for rest := node.Children[1]; isLegalNode(rest); rest = rest.Children[2] {
+		ops = append(ops, rest.Children[0].Value)
+		terms = append(terms, rest.Children[1])
+	}
+	return
+}
+
+// checkPointerArith 检查算术表达式中的指针运算，并记录需要乘以元素大小的整数项
+func (a *Analyser) checkPointerArith(node *util.TreeNode) {
+	ops, terms := arithTerms(node)
+	types := make([]string, len(terms))
+	for i, term := range terms {
+		types[i] = a.exprType(term)
+		if isLegalNode(term.Children[1]) && a.hasPointerFactor(term) {
+			a.Logger.AddAnalyseErr(firstToken(term), logger.CodePointerMulDiv, "指针不能参与乘除运算")
+			a.err = true
+		}
+	}
+	t := types[0]
+	for i := 1; i < len(terms); i++ {
+		result := arithType(t, ops[i], types[i])
+		if result == "" && (isPointer(t) || isPointer(types[i])) {
+			a.Logger.AddAnalyseErr(firstToken(terms[i]), logger.CodePointerOperand, "指针运算的操作数类型不合法: ", t, ops[i], types[i])
+			a.err = true
+			return
+		}
+		if isPointer(result) {
+			size, _ := a.SymbolTable.TypeSize(elemType(result))
+			if a.scaleTerms == nil {
+				a.scaleTerms = make(map[*util.TreeNode]int)
+			}
+			if isPointer(t) {
+				a.scaleTerms[terms[i]] = size
+			} else { //整数在指针前面时，前面所有的整数项都要乘以元素大小
+				for j := 0; j < i; j++ {
+					a.scaleTerms[terms[j]] = size
+				}
+			}
+		}
+		t = result
+	}
+}
+
+// checkFloatMod 检查项中取模运算的操作数，目标代码只有整数的取模，浮点数不能取模
+func (a *Analyser) checkFloatMod(term *util.TreeNode) {
+	t := a.exprType(term.Children[0])
+	for rest := term.Children[1]; isLegalNode(rest); rest = rest.Children[2] {
+		right := a.exprType(rest.Children[1])
+		if rest.Children[0].Value == "%" {
+			if t == consts.TYPEFLOAT || right == consts.TYPEFLOAT {
+				a.Logger.AddAnalyseErr(rest.Children[0].Token, logger.CodeFloatMod, "取模运算的操作数不能是浮点数: ", t, " % ", right)
+				a.err = true
+			}
+			t = consts.TYPEINT
+			continue
+		}
+		t = commonType(t, right)
+	}
+}
+
+// hasPointerFactor 判断项中是否有指针类型的因子
+func (a *Analyser) hasPointerFactor(term *util.TreeNode) bool {
+	if isPointer(a.exprType(term.Children[0])) {
+		return true
+	}
+	for rest := term.Children[1]; isLegalNode(rest); rest = rest.Children[2] {
+		if isPointer(a.exprType(rest.Children[1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// lvalueType 求赋值表达式左值的类型
+func (a *Analyser) lvalueType(node *util.TreeNode) string {
+	if node.Children[0].Value == "*" {
+		if t := a.exprType(node.Children[1]); isPointer(t) {
+			return elemType(t)
+		}
+		return ""
+	}
+	// 左值类型保留枚举类型名，用于检查枚举值的范围
+	t, _, errToken := a.resolveField(a.symbolType(node.Children[0].Children[0].Value), memberAccessOf(node, 0))
+	if errToken != nil {
+		return ""
+	}
+	return t
+}
+
+// checkPointerAssign 检查涉及指针的赋值，指针只能接收同类型的指针或常数0
+func (a *Analyser) checkPointerAssign(t string, node *util.TreeNode) {
+	rt := a.exprType(node)
+	if !isPointer(t) && !isPointer(rt) {
+		return
+	}
+	if t == "" || rt == "" || t == rt || (isPointer(t) && isZeroLiteral(node)) {
+		return
+	}
+	a.Logger.AddAnalyseErr(firstToken(node), logger.CodePointerMismatch, "指针类型不匹配: ", t, " = ", rt)
+	a.err = true
+}
+
+// isZeroLiteral 判断表达式是否只是常数0
+func isZeroLiteral(node *util.TreeNode) bool {
+	factor := singleFactor(node)
+	return factor != nil && factor.Children[0].Value == consts.CONSTANT && factor.Children[0].Children[0].Children[0].Value == "0"
+}
+
+// singleFactor 表达式只由一个因子构成时返回该因子，否则返回nil
+func singleFactor(node *util.TreeNode) *util.TreeNode {
+	for isLegalNode(node) {
+		if node.Value == consts.FACTOR {
+			return node
+		}
+		for i := 1; i < len(node.Children); i++ {
+			if isLegalNode(node.Children[i]) {
+				return nil
+			}
+		}
+		node = node.Children[0]
+	}
+	return nil
+}
+
+// constIntValue 求整型常量表达式的值，表达式不是常量表达式或者为浮点型时返回false
+func (a *Analyser) constIntValue(node *util.TreeNode) (int, bool) {
+	v, err := a.evalConst(node)
+	return v.Int, err == nil && v.Type != consts.TYPEFLOAT
+}
+
+// termFactors 将项展开为因子的列表
+func termFactors(node *util.TreeNode) []*util.TreeNode {
+	factors := []*util.TreeNode{node.Children[0]}
+	for rest := node.Children[1]; isLegalNode(rest); rest = rest.Children[2] {
+		factors = append(factors, rest.Children[1])
+	}
+	return factors
+}
+
+// convOps 求从一种类型转换为另一种类型需要的类型转换运算，char转换为float时先转换为int，
+// 转换为char时只保留低字节并符号扩展，转换为bool时与0比较(!=)，指针与int、bool转换为int时不需要转换
+func convOps(from, to string) []int {
+	if from == "" || to == "" || from == to {
+		return nil
+	}
+	switch to {
+	case consts.TYPEFLOAT:
+		switch from {
+		case consts.TYPECHAR:
+			return []int{consts.QUA_CTOI, consts.QUA_ITOF}
+		case consts.TYPEINT, consts.TYPEBOOL:
+			return []int{consts.QUA_ITOF}
+		}
+	case consts.TYPEINT:
+		switch from {
+		case consts.TYPECHAR:
+			return []int{consts.QUA_CTOI}
+		case consts.TYPEFLOAT:
+			return []int{consts.QUA_FTOI}
+		}
+	case consts.TYPECHAR:
+		switch from {
+		case consts.TYPEINT:
+			return []int{consts.QUA_ITOC}
+		case consts.TYPEFLOAT:
+			return []int{consts.QUA_FTOI, consts.QUA_ITOC}
+		}
+	case consts.TYPEBOOL:
+		if from == consts.TYPEINT || from == consts.TYPECHAR || from == consts.TYPEFLOAT || isPointer(from) {
+			return []int{consts.QUA_NE}
+		}
+	}
+	return nil
+}
+
+// isNarrowing 判断数值类型之间的转换是否可能丢失精度
+func isNarrowing(from, to string) bool {
+	if from == consts.TYPEBOOL || to == consts.TYPEBOOL {
+		return false
+	}
+	r1, ok1 := typeRank[from]
+	r2, ok2 := typeRank[to]
+	return ok1 && ok2 && r1 > r2
+}
+
+// warnNarrowing 对可能丢失精度的隐式转换给出警告，表达式是常量且转换后的值不变时不警告
+func (a *Analyser) warnNarrowing(node *util.TreeNode, from, to string) {
+	if !isNarrowing(from, to) {
+		return
+	}
+	if v, err := a.evalConst(node); err == nil && !v.changes(to) {
+		return
+	}
+	a.warn(WarnNarrowing, firstToken(node), logger.CodeNarrowing, "隐式转换可能丢失精度: ", from, " -> ", to)
+}
+
+// canConvert 判断能否进行显式类型转换，数值类型之间可以互相转换，指针可以与指针或整型互相转换
+func canConvert(from, to string) bool {
+	if from == "" || from == to {
+		return true
+	}
+	_, ok1 := typeRank[from]
+	_, ok2 := typeRank[to]
+	switch {
+	case ok1 && ok2:
+		return true
+	case isPointer(from) && isPointer(to):
+		return true
+	case isPointer(from):
+		return isIntegral(to)
+	case isPointer(to):
+		return isIntegral(from)
+	}
+	return false
+}
+
+// isIntLiteral 判断值是否为整型常数
+func isIntLiteral(value any) bool {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
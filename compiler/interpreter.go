@@ -0,0 +1,631 @@
+package compiler
+
+import (
+	"bufio"
+	"complier/pkg/consts"
+	"complier/util"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 解释执行的默认限制
+const (
+	defaultMaxSteps = 1000000 // 最多执行的四元式条数，防止死循环
+	maxCallDepth    = 1000    // 最大调用层数，目标代码的栈段只有4096字节
+)
+
+// Interpreter 四元式解释器，直接执行中间代码，输入输出与目标代码中的运行时库一致
+type Interpreter struct {
+	Qf          *util.QuaFormList
+	SymbolTable *SymbolTable
+	MaxSteps    int // 最多执行的四元式条数
+	ExitCode    int // 程序的退出码
+
+	in      *bufio.Reader
+	out     io.Writer
+	labels  map[string]int    // 函数名->函数定义所在四元式的索引
+	globals map[string]*cells // 全局变量以及main函数的变量和临时变量
+	frames  []*frame          // 调用栈
+	args    []any             // 已传递但还没有调用的实参
+}
+
+// cells 变量的存储单元，每个字存放一个值，结构体变量按字段的偏移占用多个字
+type cells struct {
+	words []any
+}
+
+// pointer 指针的值，指向某个变量的第index个字
+type pointer struct {
+	c     *cells
+	index int
+}
+
+// frame 函数调用的栈帧
+type frame struct {
+	fn     string            // 函数名
+	vars   map[string]*cells // 形参、局部变量和临时变量
+	ret    int               // 返回后执行的四元式索引
+	result any               // 接收返回值的变量
+}
+
+// RuntimeErr 解释执行时的错误
+type RuntimeErr struct {
+	Index int // 出错的四元式索引
+	Msg   string
+}
+
+func (e *RuntimeErr) Error() string {
+	return fmt.Sprintf("第%d条四元式运行错误: %s", e.Index, e.Msg)
+}
+
+func NewInterpreter(qf *util.QuaFormList, table *SymbolTable, in io.Reader, out io.Writer) *Interpreter {
+	return &Interpreter{
+		Qf:          qf,
+		SymbolTable: table,
+		MaxSteps:    defaultMaxSteps,
+		in:          bufio.NewReader(in),
+		out:         out,
+	}
+}
+
+// Run 从第一条四元式开始执行，直到sys或者四元式结束
+func (it *Interpreter) Run() error {
+	it.labels = make(map[string]int)
+	it.globals = make(map[string]*cells)
+	it.frames = nil
+	it.args = nil
+	it.ExitCode = 0
+	for name, info := range it.SymbolTable.VarTable[consts.ALL] { //初值为常量的全局变量没有初始化的四元式
+		if v, ok := literal(fmt.Sprint(info.Value)); ok && info.constInit {
+			it.globals[name] = &cells{words: []any{v}}
+		}
+	}
+	for i, form := range it.Qf.QuaForms {
+		if name, ok := form.Op.(string); ok && it.isFunc(name) {
+			it.labels[name] = i
+		}
+	}
+
+	forms := it.Qf.QuaForms
+	for pc, steps := 0, 0; pc < len(forms); steps++ {
+		if steps >= it.MaxSteps {
+			return &RuntimeErr{pc, fmt.Sprintf("执行超过%d条四元式，可能存在死循环", it.MaxSteps)}
+		}
+		next, err := it.exec(pc, forms[pc])
+		if err != nil {
+			return &RuntimeErr{pc, err.Error()}
+		}
+		if next < 0 {
+			return nil
+		}
+		pc = next
+	}
+	return nil
+}
+
+// isFunc 判断是否为用户定义的函数名
+func (it *Interpreter) isFunc(name string) bool {
+	info, ok := it.SymbolTable.FuncTable[name]
+	return ok && !info.builtin
+}
+
+// exec 执行一条四元式，返回下一条四元式的索引，返回-1表示程序结束
+func (it *Interpreter) exec(pc int, form *util.QuaForm) (int, error) {
+	op, _ := form.Op.(string)
+	arg1, arg2, result := form.Arg1, form.Arg2, form.Result
+	switch op {
+	case "=", "#":
+		it.store(result, it.value(arg1))
+	case "+", "-", "*", "/", "%":
+		v, err := arith(op, it.value(arg1), it.value(arg2))
+		if err != nil {
+			return 0, err
+		}
+		it.store(result, v)
+	case "@":
+		v, err := arith("-", 0, it.value(arg1))
+		if err != nil {
+			return 0, err
+		}
+		it.store(result, v)
+	case "<", "<=", ">", ">=", "==", "!=":
+		it.store(result, boolInt(compare(op, it.value(arg1), it.value(arg2))))
+	case "&&":
+		it.store(result, boolInt(truth(it.value(arg1)) && truth(it.value(arg2))))
+	case "||":
+		it.store(result, boolInt(truth(it.value(arg1)) || truth(it.value(arg2))))
+	case "!":
+		it.store(result, boolInt(!truth(it.value(arg1))))
+	case "jmp":
+		return jumpTarget(result)
+	case "jz", "jnz":
+		if truth(it.value(arg1)) == (op == "jnz") {
+			return jumpTarget(result)
+		}
+	case "j<", "j<=", "j>", "j>=", "j==", "j!=":
+		if compare(op[1:], it.value(arg1), it.value(arg2)) {
+			return jumpTarget(result)
+		}
+	case "itof":
+		it.store(result, float64(float32(number(it.value(arg1)))))
+	case "ftoi":
+		it.store(result, wrap(int(math.Trunc(number(it.value(arg1))))))
+	case "ctoi", "itoc":
+		it.store(result, int(int8(toInt(it.value(arg1)))))
+	case ".":
+		it.store(result, it.cellsOf(arg1).get(toInt(arg2)/2))
+	case ".=":
+		it.cellsOf(result).set(toInt(arg2)/2, it.value(arg1))
+	case "&":
+		it.store(result, pointer{it.cellsOf(arg1), toInt(arg2) / 2})
+	case "deref":
+		p, ok := it.value(arg1).(pointer)
+		if !ok {
+			return 0, fmt.Errorf("访问非法的指针: %v", arg1)
+		}
+		it.store(result, p.c.get(p.index))
+	case "deref=":
+		p, ok := it.value(result).(pointer)
+		if !ok {
+			return 0, fmt.Errorf("访问非法的指针: %v", result)
+		}
+		p.c.set(p.index, it.value(arg1))
+	case "para":
+		it.args = append(it.args, it.value(arg1))
+	case "call":
+		return it.call(pc, arg1.(string), result)
+	case "ret":
+		return it.ret(result), nil
+	case "sys":
+		if result != nil {
+			it.ExitCode = toInt(it.value(result)) & 0xff
+		}
+		return -1, nil
+	}
+	return pc + 1, nil // 函数定义的四元式只是标号
+}
+
+// call 调用函数，实参按从后往前的顺序传递
+func (it *Interpreter) call(pc int, name string, result any) (int, error) {
+	args := make([]any, len(it.args))
+	for i, arg := range it.args {
+		args[len(args)-1-i] = arg
+	}
+	it.args = nil
+	if !it.isFunc(name) {
+		v, err := it.builtin(name, args)
+		if err != nil {
+			return 0, err
+		}
+		if result != nil {
+			it.store(result, v)
+		}
+		return pc + 1, nil
+	}
+	start, ok := it.labels[name]
+	if !ok {
+		return 0, fmt.Errorf("函数未定义: %s", name)
+	}
+	if len(it.frames) >= maxCallDepth {
+		return 0, fmt.Errorf("调用层数超过%d层", maxCallDepth)
+	}
+	f := &frame{fn: name, vars: make(map[string]*cells), ret: pc + 1, result: result}
+	info := it.SymbolTable.FuncTable[name]
+	for i, par := range info.ParsName {
+		if i < len(args) {
+			v := args[i]
+			if i < len(info.Pars) {
+				v = it.convert(v, info.Pars[i])
+			}
+			f.vars[par] = &cells{words: []any{v}}
+		}
+	}
+	it.frames = append(it.frames, f)
+	return start + 1, nil
+}
+
+// ret 从函数返回，返回值保存到调用者的变量中；main函数返回时程序结束
+func (it *Interpreter) ret(result any) int {
+	if len(it.frames) == 0 {
+		return -1
+	}
+	var v any
+	if result != nil {
+		v = it.value(result)
+	}
+	f := it.frames[len(it.frames)-1]
+	it.frames = it.frames[:len(it.frames)-1]
+	if v != nil {
+		v = it.convert(v, it.SymbolTable.FuncTable[f.fn].Type)
+	}
+	if f.result != nil {
+		it.store(f.result, v)
+	}
+	return f.ret
+}
+
+// builtin 执行内置函数，输出的格式与运行时库相同
+func (it *Interpreter) builtin(name string, args []any) (any, error) {
+	switch name {
+	case "read":
+		return it.read()
+	case "write":
+		if f, ok := args[0].(float64); ok {
+			fmt.Fprint(it.out, "\nOutput:"+formatFloat(f))
+		} else {
+			fmt.Fprint(it.out, "\nOutput:"+strconv.Itoa(toInt(args[0])))
+		}
+	case "writec":
+		fmt.Fprint(it.out, string([]byte{byte(toInt(args[0]))}))
+	case "writeln":
+		fmt.Fprint(it.out, "\r\n")
+	case "prints":
+		fmt.Fprint(it.out, args[0])
+	case "printf":
+		fmt.Fprint(it.out, formatPrintf(fmt.Sprint(args[0]), args[1:]))
+	default:
+		return nil, fmt.Errorf("函数未定义: %s", name)
+	}
+	return nil, nil
+}
+
+// read 读入一个整数，输入有误时提示重新输入
+func (it *Interpreter) read() (any, error) {
+	fmt.Fprint(it.out, "\nInput:")
+	for {
+		line, err := it.in.ReadString('\n')
+		s := strings.TrimRight(line, "\r\n")
+		if n, ok := parseInput(s); ok {
+			return n, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("输入已结束")
+		}
+		fmt.Fprint(it.out, "\r\ninput error, please re-enter: ")
+	}
+}
+
+// parseInput 解析输入的整数，与运行时库一样只保留低16位
+func parseInput(s string) (int, bool) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if s == "" {
+		return 0, false
+	}
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = wrap(n*10 + int(c-'0'))
+	}
+	if neg {
+		n = wrap(-n)
+	}
+	return n, true
+}
+
+// formatPrintf 按格式串格式化实参，%后的其他字符原样输出
+func formatPrintf(format string, args []any) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			sb.WriteByte(format[i])
+			continue
+		}
+		if i+1 == len(format) {
+			break
+		}
+		i++
+		verb := format[i]
+		if !strings.ContainsRune("dcfs", rune(verb)) {
+			sb.WriteByte(verb)
+			continue
+		}
+		var arg any = 0
+		if len(args) > 0 {
+			arg, args = args[0], args[1:]
+		}
+		switch verb {
+		case 'd':
+			sb.WriteString(strconv.Itoa(toInt(arg)))
+		case 'c':
+			sb.WriteByte(byte(toInt(arg)))
+		case 'f':
+			sb.WriteString(formatFloat(number(arg)))
+		case 's':
+			sb.WriteString(fmt.Sprint(arg))
+		}
+	}
+	return sb.String()
+}
+
+// formatFloat 与运行时库一致，输出截断后的整数部分和4位小数
+func formatFloat(f float64) string {
+	s := ""
+	if f < 0 {
+		s, f = "-", -f
+	}
+	n := math.Trunc(f)
+	s += strconv.Itoa(int(uint16(int16(n)))) + "."
+	f -= n
+	for i := 0; i < 4; i++ {
+		f *= 10
+		d := math.Trunc(f)
+		s += strconv.Itoa(int(d))
+		f -= d
+	}
+	return s
+}
+
+// value 求四元式中操作数的值，常数直接解析，变量和常量从存储单元和常量表中读取
+func (it *Interpreter) value(x any) any {
+	switch v := x.(type) {
+	case nil:
+		return 0
+	case int:
+		return v
+	case float64:
+		return v
+	case string:
+		if lit, ok := literal(v); ok {
+			return lit
+		}
+		if c := it.lookup(v); c != nil {
+			return c.get(0)
+		}
+		if info, ok := it.SymbolTable.FindConstant(it.scope(), v); ok {
+			if lit, ok := literal(fmt.Sprint(info.Value)); ok {
+				return lit
+			}
+		}
+		return 0
+	}
+	return x
+}
+
+// literal 解析常数，字符常数取其编码，字符串常量去掉引号
+func literal(s string) (any, bool) {
+	if s == "" {
+		return nil, false
+	}
+	if isStringLiteral(s) {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u, true
+		}
+		return strings.Trim(s, "\""), true
+	}
+	if c, ok := charCode(s); ok {
+		return c, true
+	}
+	if s[0] != '-' && s[0] != '.' && (s[0] < '0' || s[0] > '9') {
+		return nil, false
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return wrap(i), true
+	}
+	if f, err := strconv.ParseFloat(s, 32); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// scope 当前执行的函数名
+func (it *Interpreter) scope() string {
+	if len(it.frames) == 0 {
+		return "main"
+	}
+	return it.frames[len(it.frames)-1].fn
+}
+
+// lookup 查找变量的存储单元，不存在时返回nil
+func (it *Interpreter) lookup(name string) *cells {
+	if len(it.frames) > 0 {
+		if c, ok := it.frames[len(it.frames)-1].vars[name]; ok {
+			return c
+		}
+	}
+	return it.globals[name]
+}
+
+// cellsOf 查找变量的存储单元，不存在时在其所属的作用域中分配
+func (it *Interpreter) cellsOf(x any) *cells {
+	name := fmt.Sprint(x)
+	if c := it.lookup(name); c != nil {
+		return c
+	}
+	c := &cells{}
+	if len(it.frames) > 0 {
+		f := it.frames[len(it.frames)-1]
+		if _, local := it.SymbolTable.VarTable[f.fn][name]; local || strings.HasPrefix(name, "$") {
+			f.vars[name] = c
+			return c
+		}
+	}
+	it.globals[name] = c
+	return c
+}
+
+// store 将值保存到变量中
+func (it *Interpreter) store(x any, v any) {
+	it.cellsOf(x).set(0, v)
+}
+
+func (c *cells) get(i int) any {
+	if i < 0 || i >= len(c.words) || c.words[i] == nil {
+		return 0
+	}
+	return c.words[i]
+}
+
+func (c *cells) set(i int, v any) {
+	for len(c.words) <= i {
+		c.words = append(c.words, nil)
+	}
+	c.words[i] = v
+}
+
+// arith 求算术运算的值，指针加减整数时按字节偏移，整型运算保留低16位
+func arith(op string, x, y any) (any, error) {
+	if p, ok := x.(pointer); ok {
+		if q, ok := y.(pointer); ok && op == "-" {
+			return (p.index - q.index) * 2, nil
+		}
+		switch op {
+		case "+":
+			return pointer{p.c, p.index + toInt(y)/2}, nil
+		case "-":
+			return pointer{p.c, p.index - toInt(y)/2}, nil
+		}
+	}
+	if q, ok := y.(pointer); ok && op == "+" {
+		return pointer{q.c, q.index + toInt(x)/2}, nil
+	}
+	_, f1 := x.(float64)
+	_, f2 := y.(float64)
+	if f1 || f2 {
+		a, b := number(x), number(y)
+		switch op {
+		case "+":
+			return float64(float32(a + b)), nil
+		case "-":
+			return float64(float32(a - b)), nil
+		case "*":
+			return float64(float32(a * b)), nil
+		case "/":
+			return float64(float32(a / b)), nil
+		}
+		return nil, fmt.Errorf("浮点数不能取模")
+	}
+	a, b := toInt(x), toInt(y)
+	switch op {
+	case "+":
+		return wrap(a + b), nil
+	case "-":
+		return wrap(a - b), nil
+	case "*":
+		return wrap(a * b), nil
+	}
+	if b == 0 {
+		return nil, fmt.Errorf("除数不能为0")
+	}
+	if op == "/" {
+		return wrap(a / b), nil
+	}
+	return wrap(a % b), nil
+}
+
+// compare 求关系运算的值
+func compare(op string, x, y any) bool {
+	p, ok1 := x.(pointer)
+	q, ok2 := y.(pointer)
+	if ok1 || ok2 {
+		eq := ok1 && ok2 && p == q
+		switch op {
+		case "==":
+			return eq
+		case "!=":
+			return !eq
+		}
+		return ok1 && ok2 && p.c == q.c && compare(op, p.index, q.index)
+	}
+	a, b := number(x), number(y)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "==":
+		return a == b
+	}
+	return a != b
+}
+
+// truth 值作为判断条件时的真假，非空指针为真
+func truth(v any) bool {
+	if _, ok := v.(pointer); ok {
+		return true
+	}
+	return number(v) != 0
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// number 将值转换为浮点数
+func number(v any) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// toInt 将值转换为整数，浮点数截断
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
+
+// convert 将值转换为类型t，与convOps生成的itof、ftoi、itoc和!=四元式相同；其他类型的值保持不变
+func (it *Interpreter) convert(v any, t string) any {
+	switch it.SymbolTable.underlyingType(t) {
+	case consts.TYPEFLOAT:
+		if _, ok := v.(int); ok {
+			return float64(float32(number(v)))
+		}
+	case consts.TYPEINT:
+		if f, ok := v.(float64); ok {
+			return wrap(int(math.Trunc(f)))
+		}
+	case consts.TYPECHAR: //只保留低字节并符号扩展
+		switch n := v.(type) {
+		case int:
+			return int(int8(n))
+		case float64:
+			return int(int8(wrap(int(math.Trunc(n)))))
+		}
+	case consts.TYPEBOOL:
+		return boolInt(truth(v))
+	}
+	return v
+}
+
+// wrap 保留整数的低16位
+func wrap(n int) int {
+	return int(int16(n))
+}
+
+// jumpTarget 跳转四元式的目标索引
+func jumpTarget(result any) (int, error) {
+	switch v := result.(type) {
+	case int:
+		return v, nil
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("非法的跳转目标: %v", result)
+}
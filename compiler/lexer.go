@@ -40,7 +40,7 @@ func (l *Lexer) backup() {
 
 // isOperator 判断是否是运算符
 func (l *Lexer) isOperator(r rune) bool {
-	if r == '+' || r == '-' || r == '*' || r == '/' || r == '%' || r == '>' || r == '<' || r == '=' || r == '&' || r == '|' || r == '!' || r == '(' || r == ')' || r == '[' || r == ']' {
+	if r == '+' || r == '-' || r == '*' || r == '/' || r == '%' || r == '>' || r == '<' || r == '=' || r == '&' || r == '|' || r == '!' || r == '(' || r == ')' || r == '[' || r == ']' || r == '?' || r == ':' || r == '.' {
 		return true
 	}
 	return false
@@ -139,6 +139,12 @@ func (l *Lexer) Lex() (util.Position, consts.Token, string, error) {
 			return l.pos, consts.TokenMap["["], "[", nil
 		case ']':
 			return l.pos, consts.TokenMap["]"], "]", nil
+		case '?':
+			return l.pos, consts.TokenMap["?"], "?", nil
+		case ':':
+			return l.pos, consts.TokenMap[":"], ":", nil
+		case '.':
+			return l.pos, consts.TokenMap["."], ".", nil
 		default:
 			if unicode.IsSpace(r) || r == '\r' || r == '\t' { //如果当前字符是空格,\r,\t就跳过继续扫描下一个字符
 				continue
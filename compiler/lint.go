@@ -0,0 +1,191 @@
+package compiler
+
+import (
+	"bytes"
+	"complier/pkg/logger"
+	"complier/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// LintConfigFile 项目中lint配置文件的默认文件名
+const LintConfigFile = "lint.json"
+
+// LintRule lint规则，遍历语法树时对每个节点调用Visit，通过LintContext报告诊断。
+// 规则的参数为带json标签的导出字段，由配置文件中同名规则的设置填充
+type LintRule interface {
+	Name() string                                //规则名，用于配置文件和nowarn注释
+	Visit(ctx *LintContext, node *util.TreeNode) //访问语法树中的节点
+}
+
+// SymbolRule 遍历语法树之后还需要检查符号表的规则
+type SymbolRule interface {
+	LintRule
+	CheckSymbols(ctx *LintContext)
+}
+
+// lintValidator 读取配置后需要检查参数的规则
+type lintValidator interface {
+	validate() error
+}
+
+// LintContext 规则检查时可以访问的语法树路径和符号表
+type LintContext struct {
+	a    *Analyser
+	rule string           //当前运行的规则
+	path []*util.TreeNode //从根节点到当前节点的父节点的路径
+}
+
+// SymbolTable 返回语义分析得到的符号表
+func (c *LintContext) SymbolTable() *SymbolTable {
+	return c.a.SymbolTable
+}
+
+// Ancestors 返回当前节点的所有祖先，从根节点开始
+func (c *LintContext) Ancestors() []*util.TreeNode {
+	return c.path
+}
+
+// Parent 返回当前节点的父节点，根节点的父节点为nil
+func (c *LintContext) Parent() *util.TreeNode {
+	if len(c.path) == 0 {
+		return nil
+	}
+	return c.path[len(c.path)-1]
+}
+
+// Inside 判断当前节点是否位于名为value的节点之中
+func (c *LintContext) Inside(value string) bool {
+	for _, node := range c.path {
+		if node.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Report 报告当前规则发现的问题，code为诊断码。与其他警告一样受警告选项控制，
+// 被-Wno-<规则名>或nowarn注释关闭时忽略并返回nil，-Werror或-Werror=<规则名>时作为错误报告
+func (c *LintContext) Report(token *util.TokenNode, code string, msg ...string) *logger.Diagnostic {
+	if token == nil {
+		return nil
+	}
+	return c.a.warn(c.rule, token, code, msg...)
+}
+
+// Linter 按项目配置运行lint规则
+type Linter struct {
+	rules   []LintRule      //按固定顺序排列的所有规则
+	enabled map[string]bool //规则名->是否开启
+}
+
+// NewLinter 创建使用默认配置的Linter
+func NewLinter() *Linter {
+	l := &Linter{enabled: make(map[string]bool)}
+	for _, r := range defaultLintRules() {
+		l.rules = append(l.rules, r.rule)
+		l.enabled[r.rule.Name()] = r.enabled
+	}
+	return l
+}
+
+// LoadLinter 读取项目的lint配置文件，文件不存在时使用默认配置
+func LoadLinter(path string) (*Linter, error) {
+	l := NewLinter()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = l.Configure(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Configure 读取JSON格式的配置: {"rules": {"<规则名>": {"enabled": true, <规则的参数>...}}}，没有列出的规则和参数保持默认值
+func (l *Linter) Configure(data []byte) error {
+	var config struct {
+		Rules map[string]json.RawMessage `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("lint配置格式错误: %v", err)
+	}
+	for name, raw := range config.Rules {
+		rule := l.rule(name)
+		if rule == nil {
+			return fmt.Errorf("未知的lint规则: %s", name)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return fmt.Errorf("lint规则%s的配置有误: %v", name, err)
+		}
+		if enabled, ok := fields["enabled"]; ok {
+			var on bool
+			if err := json.Unmarshal(enabled, &on); err != nil {
+				return fmt.Errorf("lint规则%s的配置有误: %v", name, err)
+			}
+			l.enabled[name] = on
+			delete(fields, "enabled")
+		}
+		params, _ := json.Marshal(fields)
+		dec := json.NewDecoder(bytes.NewReader(params))
+		dec.DisallowUnknownFields() //拼错的参数名报错，而不是静默使用默认值
+		if err := dec.Decode(rule); err != nil {
+			return fmt.Errorf("lint规则%s的配置有误: %v", name, err)
+		}
+		if v, ok := rule.(lintValidator); ok {
+			if err := v.validate(); err != nil {
+				return fmt.Errorf("lint规则%s的配置有误: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// rule 按名字查找规则
+func (l *Linter) rule(name string) LintRule {
+	for _, r := range l.rules {
+		if r.Name() == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Enabled 判断规则是否开启
+func (l *Linter) Enabled(name string) bool {
+	return l.enabled[name]
+}
+
+// Lint 在语义分析之后运行开启的lint规则，诊断按分析器的警告选项作为警告或错误加入日志
+func (a *Analyser) Lint(l *Linter) {
+	for _, rule := range l.rules {
+		if !l.enabled[rule.Name()] || !a.Warnings.Enabled(rule.Name()) {
+			continue
+		}
+		ctx := &LintContext{a: a, rule: rule.Name()}
+		ctx.walk(rule, a.Ast)
+		if s, ok := rule.(SymbolRule); ok {
+			s.CheckSymbols(ctx)
+		}
+	}
+}
+
+// walk 先序遍历语法树
+func (c *LintContext) walk(rule LintRule, node *util.TreeNode) {
+	if node == nil {
+		return
+	}
+	rule.Visit(c, node)
+	c.path = append(c.path, node)
+	for _, child := range node.Children {
+		c.walk(rule, child)
+	}
+	c.path = c.path[:len(c.path)-1]
+}
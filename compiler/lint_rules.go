@@ -0,0 +1,230 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// lint规则名，用于配置文件和nowarn注释
+const (
+	LintMagicNumber  = "magic-number"  // const声明以外的数值常数
+	LintDeepNesting  = "deep-nesting"  // 控制语句嵌套过深
+	LintLongFunction = "long-function" // 函数中的语句过多
+	LintEmptyLoop    = "empty-loop"    // 循环体为空
+	LintNaming       = "naming"        // 名字不符合命名规范
+)
+
+// lintDefault 规则及其默认是否开启
+type lintDefault struct {
+	rule    LintRule
+	enabled bool
+}
+
+// defaultLintRules 所有规则及其默认配置，规则按此顺序运行。
+// 条件中的赋值(if (x = 1))由语法分析直接报告为错误(见Parser.assignInCondition)，不是可以配置的lint规则
+func defaultLintRules() []lintDefault {
+	return []lintDefault{
+		{&magicNumber{Allowed: []float64{0, 1}}, false},
+		{&deepNesting{Max: 4}, true},
+		{&longFunction{Max: 50}, true},
+		{&emptyLoop{}, true},
+		{&naming{
+			Variable: "^[a-z][a-zA-Z0-9_]*$",
+			Constant: "^[A-Z][A-Z0-9_]*$",
+			Function: "^[a-z][a-zA-Z0-9_]*$",
+			Type:     "^[A-Z][a-zA-Z0-9]*$",
+		}, false},
+	}
+}
+
+// magicNumber 常量声明和枚举声明以外出现的数值常数，Allowed中的数除外
+type magicNumber struct {
+	Allowed []float64 `json:"allowed"`
+}
+
+func (r *magicNumber) Name() string { return LintMagicNumber }
+
+func (r *magicNumber) Visit(ctx *LintContext, node *util.TreeNode) {
+	if node.Value != consts.NUM_CONSTANT || len(node.Children) == 0 {
+		return
+	}
+	if ctx.Inside(consts.CONST_DECLARATION) || ctx.Inside(consts.ENUM_DECL) {
+		return
+	}
+	token := node.Children[0].Token
+	if token == nil {
+		return
+	}
+	if v, err := strconv.ParseFloat(token.Value, 64); err == nil {
+		for _, allowed := range r.Allowed {
+			if v == allowed {
+				return
+			}
+		}
+	}
+	ctx.Report(token, logger.CodeMagicNumber, "魔数: ", token.Value, ", 应定义为常量")
+}
+
+// deepNesting if、while、for、do while语句嵌套的层数超过Max，else if与前面的if算同一层
+type deepNesting struct {
+	Max int `json:"max"`
+}
+
+func (r *deepNesting) Name() string { return LintDeepNesting }
+
+func (r *deepNesting) validate() error {
+	if r.Max < 1 {
+		return fmt.Errorf("max必须大于0")
+	}
+	return nil
+}
+
+func (r *deepNesting) Visit(ctx *LintContext, node *util.TreeNode) {
+	if !isNesting(node, ctx.Parent()) {
+		return
+	}
+	depth := 1
+	path := ctx.Ancestors()
+	for i, n := range path {
+		var parent *util.TreeNode
+		if i > 0 {
+			parent = path[i-1]
+		}
+		if isNesting(n, parent) {
+			depth++
+		}
+	}
+	if depth == r.Max+1 { //只在第一次超过时报告，更深的语句不再重复报告
+		ctx.Report(firstToken(node), logger.CodeDeepNesting, "控制语句嵌套过深: ", fmt.Sprintf("%d层, 最多%d层", depth, r.Max))
+	}
+}
+
+// isNesting 判断节点是否是增加一层嵌套的控制语句，else if不增加嵌套
+func isNesting(node *util.TreeNode, parent *util.TreeNode) bool {
+	switch node.Value {
+	case consts.WHILE_STMT, consts.FOR_STMT, consts.DO_WHILE_STMT:
+		return true
+	case consts.IF_STMT:
+		return parent == nil || parent.Value != consts.IF_TAIL_0
+	}
+	return false
+}
+
+// longFunction 函数体中的语句数超过Max，嵌套在复合语句中的语句也计算在内
+type longFunction struct {
+	Max int `json:"max"`
+}
+
+func (r *longFunction) Name() string { return LintLongFunction }
+
+func (r *longFunction) validate() error {
+	if r.Max < 1 {
+		return fmt.Errorf("max必须大于0")
+	}
+	return nil
+}
+
+func (r *longFunction) Visit(ctx *LintContext, node *util.TreeNode) {
+	var name *util.TreeNode
+	switch node.Value {
+	case consts.FUNCTION_DEF:
+		if v := childOf(node, consts.VARIABLE); v != nil && len(v.Children) != 0 {
+			name = v.Children[0]
+		}
+	case consts.PROGRAM:
+		name = childOf(node, "main")
+	}
+	body := childOf(node, consts.COMPOUND_STMT)
+	if name == nil || body == nil {
+		return
+	}
+	if n := countNodes(body, consts.STATEMENT); n > r.Max {
+		ctx.Report(name.Token, logger.CodeLongFunction, "函数过长: ", fmt.Sprintf("%s有%d条语句, 最多%d条", name.Value, n, r.Max))
+	}
+}
+
+// countNodes 统计子树中名为value的节点个数
+func countNodes(node *util.TreeNode, value string) int {
+	n := 0
+	if node.Value == value {
+		n++
+	}
+	for _, child := range node.Children {
+		n += countNodes(child, value)
+	}
+	return n
+}
+
+// emptyLoop 循环体为空的while、for、do while语句
+type emptyLoop struct{}
+
+func (r *emptyLoop) Name() string { return LintEmptyLoop }
+
+func (r *emptyLoop) Visit(ctx *LintContext, node *util.TreeNode) {
+	switch node.Value {
+	case consts.WHILE_STMT, consts.FOR_STMT, consts.DO_WHILE_STMT:
+	default:
+		return
+	}
+	body := childOf(node, consts.COMPOUND_STMT)
+	if body == nil || isLegalNode(childOf(body, consts.STATEMENT_TABLE)) {
+		return
+	}
+	ctx.Report(firstToken(node), logger.CodeEmptyLoop, "循环体为空")
+}
+
+// naming 变量、常量、函数和类型的名字需要匹配对应的正则表达式，表达式为空时不检查
+type naming struct {
+	Variable string `json:"variable"` //变量和形参
+	Constant string `json:"constant"` //常量和枚举常量
+	Function string `json:"function"` //函数，main除外
+	Type     string `json:"type"`     //结构体和枚举类型
+}
+
+func (r *naming) Name() string { return LintNaming }
+
+func (r *naming) validate() error {
+	for _, pattern := range []string{r.Variable, r.Constant, r.Function, r.Type} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *naming) Visit(*LintContext, *util.TreeNode) {}
+
+// CheckSymbols 按符号表的顺序检查所有用户定义的名字
+func (r *naming) CheckSymbols(ctx *LintContext) {
+	s := ctx.SymbolTable()
+	check := func(pattern string, name string, token *util.TokenNode) {
+		if pattern == "" || token == nil {
+			return
+		}
+		if ok, _ := regexp.MatchString(pattern, name); !ok { //配置时已经检查过表达式
+			ctx.Report(token, logger.CodeNaming, "名字不符合命名规范: ", name, ", 应匹配 ", pattern)
+		}
+	}
+	for _, info := range s.symbols() {
+		switch s.kindOf(info) {
+		case KindVariable, KindParameter:
+			check(r.Variable, sourceName(info.Name), info.token)
+		case KindConstant:
+			check(r.Constant, info.Name, info.token)
+		case KindFunction:
+			if info.Name != "main" {
+				check(r.Function, info.Name, info.definition())
+			}
+		}
+	}
+	for _, t := range s.types() {
+		check(r.Type, t.Name, t.token)
+	}
+	for _, e := range s.enums() {
+		check(r.Type, e.Name, e.token)
+	}
+}
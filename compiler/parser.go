@@ -7,10 +7,11 @@ import (
 )
 
 type Parser struct {
-	Token  []util.TokenNode // token列表
-	Index  int              //当前的token下标
-	Logger *logger.Logger   // 日志
-	AST    *util.TreeNode   // 语法树根节点
+	Token    []util.TokenNode // token列表
+	Comments []util.TokenNode // 注释，语义分析时从中读取nowarn指令
+	Index    int              //当前的token下标
+	Logger   *logger.Logger   // 日志
+	AST      *util.TreeNode   // 语法树根节点
 }
 
 func NewParser() *Parser {
@@ -47,6 +48,17 @@ func (p *Parser) match(token util.TokenNode, expectToken consts.Token) bool {
 	return token.Type == expectToken
 }
 
+// assignInCondition 判断条件之后是否是=。文法中条件只能是布尔表达式，if (x = 1)多半是把==误写成了=，
+// 此时报告错误并给出替换为==的建议，再跳过=右边的表达式，之后继续匹配条件后面的符号
+func (p *Parser) assignInCondition(token util.TokenNode, nodeName string) bool {
+	if !p.match(token, consts.TokenMap["="]) {
+		return false
+	}
+	p.Logger.AddSyntaxErr(token, nodeName, logger.CodeAssignInCond, "条件中不能赋值, 比较是否相等应使用 ==").WithFix(logger.SpanOf(&token), "==", "替换为 ==")
+	p.boolExp()
+	return true
+}
+
 // isFinish 判断程序是否读取结束
 func (p *Parser) isFinish(token util.TokenNode) bool {
 	return p.match(token, consts.TokenMap["EOF"])
@@ -55,19 +67,37 @@ func (p *Parser) isFinish(token util.TokenNode) bool {
 // isFuncType 判断token是否是函数类型
 func (p *Parser) isFuncType(token util.TokenNode) bool {
 	t := token.Type
-	return t == consts.TokenMap["int"] || t == consts.TokenMap["char"] || t == consts.TokenMap["float"] || t == consts.TokenMap["void"]
+	return t == consts.TokenMap["int"] || t == consts.TokenMap["char"] || t == consts.TokenMap["float"] || t == consts.TokenMap["bool"] || t == consts.TokenMap["void"]
+}
+
+// isMainType 判断token是否是main函数的返回类型
+func (p *Parser) isMainType(token util.TokenNode) bool {
+	return token.Type == consts.TokenMap["int"] || token.Type == consts.TokenMap["void"]
+}
+
+// isFunctionDefine 向前查看到形参列表之后，后面是{时为函数定义，是;时为函数声明
+func (p *Parser) isFunctionDefine() bool {
+	if !p.isFuncType(p.peek(1)) || !p.match(p.peek(2), consts.TokenMap["identifier"]) || !p.match(p.peek(3), consts.TokenMap["("]) {
+		return false
+	}
+	for n := 4; !p.match(p.peek(n), consts.TokenMap["EOF"]); n++ {
+		if p.match(p.peek(n), consts.TokenMap[")"]) {
+			return p.match(p.peek(n+1), consts.TokenMap["{"])
+		}
+	}
+	return false
 }
 
 // isConstType 判断token是否是常数类型
 func (p *Parser) isConstType(token util.TokenNode) bool {
 	t := token.Type
-	return t == consts.TokenMap["integer"] || t == consts.TokenMap["floatnumber"] || t == consts.TokenMap["character"]
+	return t == consts.TokenMap["integer"] || t == consts.TokenMap["floatnumber"] || t == consts.TokenMap["character"] || t == consts.TokenMap["true"] || t == consts.TokenMap["false"]
 }
 
 // isVarType 判断token是否是变量类型
 func (p *Parser) isVarType(token util.TokenNode) bool {
 	t := token.Type
-	return t == consts.TokenMap["int"] || t == consts.TokenMap["float"] || t == consts.TokenMap["char"]
+	return t == consts.TokenMap["int"] || t == consts.TokenMap["float"] || t == consts.TokenMap["char"] || t == consts.TokenMap["bool"]
 }
 
 // isRelaOpe 判断token是否是关系运算符
@@ -82,10 +112,39 @@ func (p *Parser) isDeclarationValue(token util.TokenNode) bool {
 	return t == consts.TokenMap["const"] || t == consts.TokenMap["var"]
 }
 
-// isStatement 判断token是否是执行语句
+// isStatement 判断token是否是执行语句，以常数、(或单目运算符开头的语句不合法，但仍按执行语句分析，以便报告赋值号左边不是左值
 func (p *Parser) isExeStatement(token util.TokenNode) bool {
 	t := token.Type
-	return t == consts.TokenMap["{"] || t == consts.TokenMap["identifier"] || t == consts.TokenMap["if"] || t == consts.TokenMap["do"] || t == consts.TokenMap["while"] || t == consts.TokenMap["for"] || t == consts.TokenMap["return"] || t == consts.TokenMap["continue"] || t == consts.TokenMap["break"]
+	return t == consts.TokenMap["{"] || t == consts.TokenMap["identifier"] || t == consts.TokenMap["*"] || t == consts.TokenMap["if"] || t == consts.TokenMap["do"] || t == consts.TokenMap["while"] || t == consts.TokenMap["for"] || t == consts.TokenMap["return"] || t == consts.TokenMap["continue"] || t == consts.TokenMap["break"] || p.isExpStart(token)
+}
+
+// isExpStart 判断token是否只能是表达式的开头，不能作为执行语句的开头
+func (p *Parser) isExpStart(token util.TokenNode) bool {
+	return p.isConstType(token) || p.match(token, consts.TokenMap["("]) || p.isPrefixOperator(token) && !p.match(token, consts.TokenMap["*"])
+}
+
+// invalidLvalue 赋值号左边是表达式而不是变量时，如3 = a;、(a) = 1;，报告错误并跳过整个赋值语句。
+// 先试探着把左边分析为布尔表达式，后面不是=时回退并返回false
+func (p *Parser) invalidLvalue(nodeName string) bool {
+	index, errs := p.Index, len(p.Logger.Errs)
+	start := p.peek(1)
+	p.boolExp()
+	if p.Index == index || !p.match(p.peek(1), consts.TokenMap["="]) {
+		p.Index = index
+		p.Logger.Errs = p.Logger.Errs[:errs]
+		return false
+	}
+	p.Logger.Errs = p.Logger.Errs[:errs]
+	d := p.Logger.AddSyntaxErr(start, nodeName, logger.CodeNotLvalue, "赋值号左边不是左值")
+	if last := p.Token[p.Index-1]; last.Pos.Line == start.Pos.Line { //标出整个左边的表达式
+		d.Span.EndColumn = logger.SpanOf(&last).EndColumn
+	}
+	p.nextToken()
+	p.boolExp()
+	if p.match(p.peek(1), consts.TokenMap[";"]) {
+		p.nextToken()
+	}
+	return true
 }
 
 // isControlStatement 判断token是否是控制语句
@@ -113,16 +172,33 @@ func (p *Parser) program() *util.TreeNode {
 		switch state {
 		case 0:
 			token = p.peek(1)
-			if p.match(token, consts.TokenMap["main"]) {
-				state = 1
+			if p.match(token, consts.TokenMap["main"]) || p.isMainType(token) && p.match(p.peek(2), consts.TokenMap["main"]) {
+				state = 6
+				continue
+			}
+			if p.isFunctionDefine() { //main之前的函数定义
+				if flag, node = p.functionDefine(); flag {
+					root.AddChild(node)
+				} else {
+					state = 6
+				}
 				continue
 			}
 			flag, node = p.declarationStatement()
-			if flag {
+			if flag && isLegalNode(node) {
 				root.AddChild(node)
 			} else {
-				state = 1
+				state = 6
+			}
+		case 6:
+			token = p.peek(1)
+			if p.isMainType(token) {
+				token = p.nextToken()
+				node = util.NewTreeNode(nil, consts.MAIN_TYPE)
+				node.AddChild(util.NewTreeNode(&token, token.Value))
+				root.AddChild(node)
 			}
+			state = 1
 		case 1:
 			token = p.nextToken()
 			if p.match(token, consts.TokenMap["main"]) {
@@ -130,7 +206,7 @@ func (p *Parser) program() *util.TreeNode {
 				root.AddChild(util.NewTreeNode(&token, "main"))
 			} else {
 				state = 2
-				p.Logger.AddParserErr(token, nodeName, "缺少main函数")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingMain, "缺少main函数")
 			}
 		case 2:
 			token = p.nextToken()
@@ -139,7 +215,7 @@ func (p *Parser) program() *util.TreeNode {
 				root.AddChild(util.NewTreeNode(&token, "("))
 			} else {
 				state = 3
-				p.Logger.AddParserErr(token, nodeName, "缺少 ( ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ( ")
 			}
 		case 3:
 			token = p.nextToken()
@@ -148,7 +224,7 @@ func (p *Parser) program() *util.TreeNode {
 				root.AddChild(util.NewTreeNode(&token, ")"))
 			} else {
 				state = 4
-				p.Logger.AddParserErr(token, nodeName, "缺少 ) ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ) ")
 			}
 		case 4:
 			flag, node = p.compoundStatement()
@@ -188,6 +264,10 @@ func (p *Parser) declarationStatement() (ok bool, root *util.TreeNode) {
 				state = 1
 			} else if p.isFuncType(token) {
 				state = 2
+			} else if p.match(token, consts.TokenMap["struct"]) { //结构体声明
+				state = 3
+			} else if p.match(token, consts.TokenMap["enum"]) { //枚举声明
+				state = 4
 			} else {
 				state = -1
 				node = util.NewTreeNode(nil, consts.NULL)
@@ -209,6 +289,22 @@ func (p *Parser) declarationStatement() (ok bool, root *util.TreeNode) {
 				state = -1
 				ok = false
 			}
+		case 3:
+			if flag, node = p.structDeclaration(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		case 4:
+			if flag, node = p.enumDeclaration(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
 		}
 	}
 
@@ -236,7 +332,7 @@ func (p *Parser) compoundStatement() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 { ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 { ")
 			}
 		case 1:
 			//token = p.peek(1)
@@ -259,7 +355,7 @@ func (p *Parser) compoundStatement() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 } ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 } ")
 			}
 		}
 	}
@@ -362,7 +458,7 @@ func (p *Parser) statement() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName)
+				p.Logger.AddParserErr(token, nodeName, logger.CodeSyntax)
 			}
 		case 1:
 			if flag, node = p.declarationValue(); flag {
@@ -401,14 +497,16 @@ func (p *Parser) exeStatement() (ok bool, root *util.TreeNode) {
 			token = p.peek(1)
 			if p.match(token, consts.TokenMap["{"]) {
 				state = 1
-			} else if p.match(token, consts.TokenMap["identifier"]) {
+			} else if p.match(token, consts.TokenMap["identifier"]) || p.match(token, consts.TokenMap["*"]) {
 				state = 2
 			} else if p.isControlStatement(token) {
 				state = 3
+			} else if p.invalidLvalue(nodeName) {
+				state = -1
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName)
+				p.Logger.AddParserErr(token, nodeName, logger.CodeSyntax)
 			}
 		case 1:
 			if flag, node = p.compoundStatement(); flag {
@@ -455,21 +553,25 @@ func (p *Parser) dataHandleStatement() (ok bool, root *util.TreeNode) {
 			token = p.peek(1)
 			if p.match(token, consts.TokenMap["identifier"]) {
 				state = 1
+			} else if p.match(token, consts.TokenMap["*"]) { //通过指针赋值
+				state = 2
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少标识符")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingIdent, "缺少标识符")
 			}
 		case 1:
 			token = p.peek(2)
-			if p.match(token, consts.TokenMap["="]) {
+			if p.match(token, consts.TokenMap["="]) || p.match(token, consts.TokenMap["."]) {
 				state = 2
 			} else if p.match(token, consts.TokenMap["("]) {
 				state = 3
+			} else if p.invalidLvalue(nodeName) {
+				state = -1
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 = 或 ( ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 = 或 ( ")
 			}
 		case 2:
 			if flag, node = p.assignmentStatement(); flag {
@@ -570,7 +672,7 @@ func (p *Parser) functionDefine() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 3
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 (")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 (")
 			}
 		case 3:
 			if flag, node = p.defineFormalParamList(); flag {
@@ -589,7 +691,7 @@ func (p *Parser) functionDefine() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 5
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 )")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 )")
 			}
 		case 5:
 			if flag, node = p.compoundStatement(); flag {
@@ -625,7 +727,7 @@ func (p *Parser) declarationValue() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少值声明关键字")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少值声明关键字")
 			}
 		case 1:
 			if flag, node = p.declarationConst(); flag {
@@ -678,7 +780,7 @@ func (p *Parser) declarationFunctionStatement() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; ")
 			}
 		}
 	}
@@ -721,7 +823,7 @@ func (p *Parser) declarationFunction() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, " ( 缺失")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, " ( 缺失")
 			}
 		case 3:
 			if flag, node = p.declFormalParamList(); flag {
@@ -740,7 +842,7 @@ func (p *Parser) declarationFunction() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, " ) 缺失")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, " ) 缺失")
 			}
 		}
 	}
@@ -766,7 +868,7 @@ func (p *Parser) declarationConst() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少关键字const")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少关键字const")
 			}
 		case 1:
 			if flag, node = p.constType(); flag {
@@ -808,7 +910,7 @@ func (p *Parser) constType() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "类型缺失")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingType, "类型缺失")
 			}
 		}
 	}
@@ -843,7 +945,7 @@ func (p *Parser) declarationConstTable() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 2
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 = ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 = ")
 			}
 		case 2:
 			if flag, node = p.declarationConstTable0(); flag {
@@ -914,7 +1016,7 @@ func (p *Parser) declarationConstTable1() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ; 或 ,")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; 或 ,")
 			}
 		case 1:
 			if flag, node = p.declarationConstTable(); flag {
@@ -935,32 +1037,12 @@ func (p *Parser) declarationConstTableValue() (ok bool, root *util.TreeNode) {
 	nodeName := consts.CONST_TABLE_VALUE
 	root = util.NewTreeNode(nil, nodeName)
 	state := 0
-	var token util.TokenNode
 	var flag bool
 	var node *util.TreeNode
 	for state != -1 {
 		switch state {
 		case 0:
-			token = p.peek(1)
-			if p.match(token, consts.TokenMap["identifier"]) {
-				state = 1
-			} else if p.isConstType(token) {
-				state = 2
-			} else {
-				state = -1
-				ok = false
-				p.Logger.AddParserErr(token, nodeName)
-			}
-		case 1:
-			if flag, node = p.Var(); flag {
-				state = -1
-				root.AddChild(node)
-			} else {
-				state = -1
-				ok = false
-			}
-		case 2:
-			if flag, node = p.Const(); flag {
+			if flag, node = p.boolExp(); flag {
 				state = -1
 				root.AddChild(node)
 			} else {
@@ -991,7 +1073,7 @@ func (p *Parser) Var() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少标识符")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingIdent, "缺少标识符")
 			}
 		}
 	}
@@ -1013,12 +1095,14 @@ func (p *Parser) Const() (ok bool, root *util.TreeNode) {
 			token = p.peek(1)
 			if p.match(token, consts.TokenMap["character"]) {
 				state = 1
+			} else if p.match(token, consts.TokenMap["true"]) || p.match(token, consts.TokenMap["false"]) {
+				state = 3
 			} else if p.isConstType(token) {
 				state = 2
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少常量")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingConst, "缺少常量")
 			}
 		case 1:
 			if flag, node = p.charConst(); flag {
@@ -1036,6 +1120,14 @@ func (p *Parser) Const() (ok bool, root *util.TreeNode) {
 				state = -1
 				ok = false
 			}
+		case 3:
+			if flag, node = p.boolConst(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
 		}
 	}
 	return
@@ -1064,7 +1156,7 @@ func (p *Parser) numberConst() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少数值型常量")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingConst, "缺少数值型常量")
 			}
 		}
 	}
@@ -1090,17 +1182,17 @@ func (p *Parser) charConst() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少字符型常量")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingConst, "缺少字符型常量")
 			}
 		}
 	}
 	return
 }
 
-// funcType <函数类型>
-func (p *Parser) funcType() (ok bool, root *util.TreeNode) {
+// boolConst <布尔型常量>
+func (p *Parser) boolConst() (ok bool, root *util.TreeNode) {
 	ok = true
-	nodeName := consts.FUNCTION_TYPE
+	nodeName := consts.BOOL_CONSTANT
 	root = util.NewTreeNode(nil, nodeName)
 	var token util.TokenNode
 	state := 0
@@ -1109,24 +1201,24 @@ func (p *Parser) funcType() (ok bool, root *util.TreeNode) {
 		switch state {
 		case 0:
 			token = p.nextToken()
-			if p.isFuncType(token) {
+			if p.match(token, consts.TokenMap["true"]) || p.match(token, consts.TokenMap["false"]) {
 				state = -1
 				node = util.NewTreeNode(&token, token.Value)
 				root.AddChild(node)
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少函数类型")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingConst, "缺少布尔型常量")
 			}
 		}
 	}
 	return
 }
 
-// declarationVar <变量声明>
-func (p *Parser) declarationVar() (ok bool, root *util.TreeNode) {
+// structDeclaration <结构体声明>
+func (p *Parser) structDeclaration() (ok bool, root *util.TreeNode) {
 	ok = true
-	nodeName := consts.VARIABLE_DECL
+	nodeName := consts.STRUCT_DECL
 	root = util.NewTreeNode(nil, nodeName)
 	state := 0
 	var flag bool
@@ -1136,17 +1228,17 @@ func (p *Parser) declarationVar() (ok bool, root *util.TreeNode) {
 		switch state {
 		case 0:
 			token = p.nextToken()
-			if p.match(token, consts.TokenMap["var"]) {
+			if p.match(token, consts.TokenMap["struct"]) {
 				state = 1
-				node = util.NewTreeNode(&token, "var")
+				node = util.NewTreeNode(&token, "struct")
 				root.AddChild(node)
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少关键字 var ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少关键字 struct ")
 			}
 		case 1:
-			if flag, node = p.varType(); flag {
+			if flag, node = p.Var(); flag {
 				state = 2
 				root.AddChild(node)
 			} else {
@@ -1154,64 +1246,126 @@ func (p *Parser) declarationVar() (ok bool, root *util.TreeNode) {
 				ok = false
 			}
 		case 2:
-			if flag, node = p.declarationVarTable(); flag {
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["{"]) {
+				state = 3
+				node = util.NewTreeNode(&token, "{")
+				root.AddChild(node)
+			} else {
+				state = 3
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 { ")
+			}
+		case 3:
+			if flag, node = p.structMembers(); flag {
+				state = 4
+				root.AddChild(node)
+			} else {
+				state = 4
+				ok = false
+			}
+		case 4:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["}"]) {
+				state = 5
+				node = util.NewTreeNode(&token, "}")
+				root.AddChild(node)
+			} else {
+				state = 5
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 } ")
+			}
+		case 5:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap[";"]) {
 				state = -1
+				node = util.NewTreeNode(&token, ";")
 				root.AddChild(node)
 			} else {
+				p.backup()
 				state = -1
 				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; ")
 			}
 		}
 	}
 	return
 }
 
-// varType <变量类型>
-func (p *Parser) varType() (ok bool, root *util.TreeNode) {
+// structMembers <结构体成员表>
+func (p *Parser) structMembers() (ok bool, root *util.TreeNode) {
 	ok = true
-	nodeName := consts.VARIABLE_TYPE
+	nodeName := consts.STRUCT_MEMBERS
 	root = util.NewTreeNode(nil, nodeName)
 	state := 0
+	var flag bool
 	var node *util.TreeNode
 	var token util.TokenNode
 	for state != -1 {
 		switch state {
 		case 0:
+			if flag, node = p.varType(); flag {
+				state = 1
+				root.AddChild(node)
+			} else {
+				state = 1
+				ok = false
+			}
+		case 1:
+			if flag, node = p.Var(); flag {
+				state = 2
+				root.AddChild(node)
+			} else {
+				state = 2
+				ok = false
+			}
+		case 2:
 			token = p.nextToken()
-			if p.isVarType(token) {
+			if p.match(token, consts.TokenMap[";"]) {
+				state = 3
+				node = util.NewTreeNode(&token, ";")
+				root.AddChild(node)
+			} else {
+				p.backup()
+				state = -1
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; ")
+			}
+		case 3:
+			if flag, node = p.structMembers0(); flag {
 				state = -1
-				node = util.NewTreeNode(&token, token.Value)
 				root.AddChild(node)
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少变量类型")
 			}
 		}
 	}
 	return
 }
 
-// declarationVarTable <变量声明表>
-func (p *Parser) declarationVarTable() (ok bool, root *util.TreeNode) {
+// structMembers0 <结构体成员表0>
+func (p *Parser) structMembers0() (ok bool, root *util.TreeNode) {
 	ok = true
-	nodeName := consts.VARIABLE_TABLE
+	nodeName := consts.STRUCT_MEMBERS_0
 	root = util.NewTreeNode(nil, nodeName)
 	state := 0
 	var flag bool
 	var node *util.TreeNode
+	var token util.TokenNode
 	for state != -1 {
 		switch state {
 		case 0:
-			if flag, node = p.declarationSingleVar(); flag {
+			token = p.peek(1)
+			if p.isVarType(token) || p.match(token, consts.TokenMap["identifier"]) {
 				state = 1
-				root.AddChild(node)
 			} else {
-				state = 1
-				ok = false
+				state = -1
+				node = util.NewTreeNode(nil, consts.NULL)
+				root.AddChild(node)
 			}
 		case 1:
-			if flag, node = p.declarationVarTable0(); flag {
+			if flag, node = p.structMembers(); flag {
 				state = -1
 				root.AddChild(node)
 			} else {
@@ -1223,66 +1377,111 @@ func (p *Parser) declarationVarTable() (ok bool, root *util.TreeNode) {
 	return
 }
 
-// declarationSingleVar <单变量声明>
-func (p *Parser) declarationSingleVar() (ok bool, root *util.TreeNode) {
+// enumDeclaration <枚举声明>
+func (p *Parser) enumDeclaration() (ok bool, root *util.TreeNode) {
 	ok = true
-	nodeName := consts.SINGLE_VARIABLE
+	nodeName := consts.ENUM_DECL
 	root = util.NewTreeNode(nil, nodeName)
 	state := 0
 	var flag bool
 	var node *util.TreeNode
+	var token util.TokenNode
 	for state != -1 {
 		switch state {
 		case 0:
-			if flag, node = p.Var(); flag {
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["enum"]) {
 				state = 1
+				node = util.NewTreeNode(&token, "enum")
 				root.AddChild(node)
 			} else {
 				state = 1
 				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少关键字 enum ")
 			}
 		case 1:
-			if flag, node = p.declarationSingleVar0(); flag {
+			if flag, node = p.Var(); flag {
+				state = 2
+				root.AddChild(node)
+			} else {
+				state = 2
+				ok = false
+			}
+		case 2:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["{"]) {
+				state = 3
+				node = util.NewTreeNode(&token, "{")
+				root.AddChild(node)
+			} else {
+				state = 3
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 { ")
+			}
+		case 3:
+			if flag, node = p.enumMembers(); flag {
+				state = 4
+				root.AddChild(node)
+			} else {
+				state = 4
+				ok = false
+			}
+		case 4:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["}"]) {
+				state = 5
+				node = util.NewTreeNode(&token, "}")
+				root.AddChild(node)
+			} else {
+				state = 5
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 } ")
+			}
+		case 5:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap[";"]) {
 				state = -1
+				node = util.NewTreeNode(&token, ";")
 				root.AddChild(node)
 			} else {
+				p.backup()
 				state = -1
 				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; ")
 			}
 		}
 	}
 	return
 }
 
-// declarationVarTable0 <变量声明表0>
-func (p *Parser) declarationVarTable0() (ok bool, root *util.TreeNode) {
+// enumMembers <枚举成员表>
+func (p *Parser) enumMembers() (ok bool, root *util.TreeNode) {
 	ok = true
-	nodeName := consts.VARIABLE_TABLE_0
+	nodeName := consts.ENUM_MEMBERS
 	root = util.NewTreeNode(nil, nodeName)
-	var token util.TokenNode
 	state := 0
 	var flag bool
 	var node *util.TreeNode
 	for state != -1 {
 		switch state {
 		case 0:
-			token = p.nextToken()
-			if p.match(token, consts.TokenMap[";"]) {
-				state = -1
-				node = util.NewTreeNode(&token, ";")
-				root.AddChild(node)
-			} else if p.match(token, consts.TokenMap[","]) {
+			if flag, node = p.Var(); flag {
 				state = 1
-				node = util.NewTreeNode(&token, ",")
 				root.AddChild(node)
 			} else {
-				p.backup()
-				state = -1
+				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ; 或 ,")
 			}
 		case 1:
-			if flag, node = p.declarationVarTable(); flag {
+			if flag, node = p.enumValue(); flag {
+				state = 2
+				root.AddChild(node)
+			} else {
+				state = 2
+				ok = false
+			}
+		case 2:
+			if flag, node = p.enumMembers0(); flag {
 				state = -1
 				root.AddChild(node)
 			} else {
@@ -1294,10 +1493,10 @@ func (p *Parser) declarationVarTable0() (ok bool, root *util.TreeNode) {
 	return
 }
 
-// declarationSingleVar0 <单变量声明0>
-func (p *Parser) declarationSingleVar0() (ok bool, root *util.TreeNode) {
+// enumValue <枚举值>
+func (p *Parser) enumValue() (ok bool, root *util.TreeNode) {
 	ok = true
-	nodeName := consts.SINGLE_VARIABLE_0
+	nodeName := consts.ENUM_VALUE
 	root = util.NewTreeNode(nil, nodeName)
 	state := 0
 	var flag bool
@@ -1306,13 +1505,13 @@ func (p *Parser) declarationSingleVar0() (ok bool, root *util.TreeNode) {
 	for state != -1 {
 		switch state {
 		case 0:
-			token = p.nextToken()
+			token = p.peek(1)
 			if p.match(token, consts.TokenMap["="]) {
+				p.nextToken()
 				state = 1
 				node = util.NewTreeNode(&token, "=")
 				root.AddChild(node)
 			} else {
-				p.backup()
 				state = -1
 				node = util.NewTreeNode(nil, consts.NULL)
 				root.AddChild(node)
@@ -1330,18 +1529,332 @@ func (p *Parser) declarationSingleVar0() (ok bool, root *util.TreeNode) {
 	return
 }
 
-// arithmeticExp <算术表达式>
-func (p *Parser) arithmeticExp() (ok bool, root *util.TreeNode) {
+// enumMembers0 <枚举成员表0>
+func (p *Parser) enumMembers0() (ok bool, root *util.TreeNode) {
 	ok = true
-	nodeName := consts.ARITHMETIC_EXPR
+	nodeName := consts.ENUM_MEMBERS_0
 	root = util.NewTreeNode(nil, nodeName)
 	state := 0
 	var flag bool
 	var node *util.TreeNode
+	var token util.TokenNode
 	for state != -1 {
 		switch state {
 		case 0:
-			if flag, node = p.item(); flag {
+			token = p.peek(1)
+			if p.match(token, consts.TokenMap[","]) {
+				p.nextToken()
+				state = 1
+				node = util.NewTreeNode(&token, ",")
+				root.AddChild(node)
+			} else {
+				state = -1
+				node = util.NewTreeNode(nil, consts.NULL)
+				root.AddChild(node)
+			}
+		case 1:
+			if flag, node = p.enumMembers(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// funcType <函数类型>
+func (p *Parser) funcType() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.FUNCTION_TYPE
+	root = util.NewTreeNode(nil, nodeName)
+	var token util.TokenNode
+	state := 0
+	var node *util.TreeNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.nextToken()
+			if p.isFuncType(token) {
+				state = -1
+				node = util.NewTreeNode(&token, token.Value)
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingType, "缺少函数类型")
+			}
+		}
+	}
+	return
+}
+
+// declarationVar <变量声明>
+func (p *Parser) declarationVar() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.VARIABLE_DECL
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	var token util.TokenNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["var"]) {
+				state = 1
+				node = util.NewTreeNode(&token, "var")
+				root.AddChild(node)
+			} else {
+				state = 1
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少关键字 var ")
+			}
+		case 1:
+			if flag, node = p.varType(); flag {
+				state = 2
+				root.AddChild(node)
+			} else {
+				state = 2
+				ok = false
+			}
+		case 2:
+			if flag, node = p.declarationVarTable(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// varType <变量类型>
+func (p *Parser) varType() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.VARIABLE_TYPE
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	var token util.TokenNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.nextToken()
+			if p.isVarType(token) || p.match(token, consts.TokenMap["identifier"]) { //标识符为结构体类型名
+				state = 1
+				node = util.NewTreeNode(&token, token.Value)
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingType, "缺少变量类型")
+			}
+		case 1:
+			if flag, node = p.pointer(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// pointer <指针>
+func (p *Parser) pointer() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.POINTER
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	var token util.TokenNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.peek(1)
+			if p.match(token, consts.TokenMap["*"]) {
+				p.nextToken()
+				state = 1
+				node = util.NewTreeNode(&token, "*")
+				root.AddChild(node)
+			} else {
+				state = -1
+				node = util.NewTreeNode(nil, consts.NULL)
+				root.AddChild(node)
+			}
+		case 1:
+			if flag, node = p.pointer(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// declarationVarTable <变量声明表>
+func (p *Parser) declarationVarTable() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.VARIABLE_TABLE
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	for state != -1 {
+		switch state {
+		case 0:
+			if flag, node = p.declarationSingleVar(); flag {
+				state = 1
+				root.AddChild(node)
+			} else {
+				state = 1
+				ok = false
+			}
+		case 1:
+			if flag, node = p.declarationVarTable0(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// declarationSingleVar <单变量声明>
+func (p *Parser) declarationSingleVar() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.SINGLE_VARIABLE
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	for state != -1 {
+		switch state {
+		case 0:
+			if flag, node = p.Var(); flag {
+				state = 1
+				root.AddChild(node)
+			} else {
+				state = 1
+				ok = false
+			}
+		case 1:
+			if flag, node = p.declarationSingleVar0(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// declarationVarTable0 <变量声明表0>
+func (p *Parser) declarationVarTable0() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.VARIABLE_TABLE_0
+	root = util.NewTreeNode(nil, nodeName)
+	var token util.TokenNode
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap[";"]) {
+				state = -1
+				node = util.NewTreeNode(&token, ";")
+				root.AddChild(node)
+			} else if p.match(token, consts.TokenMap[","]) {
+				state = 1
+				node = util.NewTreeNode(&token, ",")
+				root.AddChild(node)
+			} else {
+				p.backup()
+				state = -1
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; 或 ,")
+			}
+		case 1:
+			if flag, node = p.declarationVarTable(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// declarationSingleVar0 <单变量声明0>
+func (p *Parser) declarationSingleVar0() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.SINGLE_VARIABLE_0
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	var token util.TokenNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["="]) {
+				state = 1
+				node = util.NewTreeNode(&token, "=")
+				root.AddChild(node)
+			} else {
+				p.backup()
+				state = -1
+				node = util.NewTreeNode(nil, consts.NULL)
+				root.AddChild(node)
+			}
+		case 1:
+			if flag, node = p.boolExp(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// arithmeticExp <算术表达式>
+func (p *Parser) arithmeticExp() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.ARITHMETIC_EXPR
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	for state != -1 {
+		switch state {
+		case 0:
+			if flag, node = p.item(); flag {
 				state = 1
 				root.AddChild(node)
 			} else {
@@ -1509,7 +2022,9 @@ func (p *Parser) factor() (ok bool, root *util.TreeNode) {
 		switch state {
 		case 0:
 			token = p.peek(1)
-			if p.match(token, consts.TokenMap["("]) {
+			if p.match(token, consts.TokenMap["("]) && p.isVarType(p.peek(2)) { //类型转换
+				state = 6
+			} else if p.match(token, consts.TokenMap["("]) {
 				p.nextToken()
 				state = 1
 				node = util.NewTreeNode(&token, "(")
@@ -1522,12 +2037,12 @@ func (p *Parser) factor() (ok bool, root *util.TreeNode) {
 				} else {
 					state = 3
 				}
-			} else if p.match(token, consts.TokenMap["-"]) || p.match(token, consts.TokenMap["+"]) || p.match(token, consts.TokenMap["!"]) {
+			} else if p.isPrefixOperator(token) {
 				state = 6
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName)
+				p.Logger.AddParserErr(token, nodeName, logger.CodeSyntax)
 			}
 		case 1:
 			if flag, node = p.boolExp(); flag {
@@ -1547,7 +2062,7 @@ func (p *Parser) factor() (ok bool, root *util.TreeNode) {
 			}
 		case 3:
 			if flag, node = p.Var(); flag {
-				state = -1
+				state = 7
 				root.AddChild(node)
 			} else {
 				state = -1
@@ -1562,7 +2077,7 @@ func (p *Parser) factor() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ) ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ) ")
 			}
 		case 5:
 			if flag, node = p.funcCall(); flag {
@@ -1580,6 +2095,58 @@ func (p *Parser) factor() (ok bool, root *util.TreeNode) {
 				state = -1
 				ok = false
 			}
+		case 7:
+			if flag, node = p.memberAccess(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// memberAccess <成员访问>
+func (p *Parser) memberAccess() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.MEMBER_ACCESS
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	var token util.TokenNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.peek(1)
+			if p.match(token, consts.TokenMap["."]) {
+				p.nextToken()
+				state = 1
+				node = util.NewTreeNode(&token, ".")
+				root.AddChild(node)
+			} else {
+				state = -1
+				node = util.NewTreeNode(nil, consts.NULL)
+				root.AddChild(node)
+			}
+		case 1:
+			if flag, node = p.Var(); flag {
+				state = 2
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		case 2:
+			if flag, node = p.memberAccess(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
 		}
 	}
 	return
@@ -1598,14 +2165,22 @@ func (p *Parser) factor0() (ok bool, root *util.TreeNode) {
 		switch state {
 		case 0:
 			token = p.nextToken()
-			if p.match(token, consts.TokenMap["+"]) || p.match(token, consts.TokenMap["-"]) || p.match(token, consts.TokenMap["!"]) {
+			if p.match(token, consts.TokenMap["+"]) || p.match(token, consts.TokenMap["-"]) || p.match(token, consts.TokenMap["!"]) || p.match(token, consts.TokenMap["*"]) {
 				state = 1
 				node = util.NewTreeNode(&token, token.Value)
 				root.AddChild(node)
+			} else if p.match(token, consts.TokenMap["&"]) { //取地址只能作用于变量或结构体成员
+				state = 2
+				node = util.NewTreeNode(&token, token.Value)
+				root.AddChild(node)
+			} else if p.match(token, consts.TokenMap["("]) {
+				state = 4
+				node = util.NewTreeNode(&token, token.Value)
+				root.AddChild(node)
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "因子0缺少 + 或 - 或 !")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "因子0缺少 + 或 - 或 ! 或 * 或 & 或 (")
 			}
 		case 1:
 			if flag, node = p.factor(); flag {
@@ -1615,6 +2190,41 @@ func (p *Parser) factor0() (ok bool, root *util.TreeNode) {
 				state = -1
 				ok = false
 			}
+		case 2:
+			if flag, node = p.Var(); flag {
+				state = 3
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		case 3:
+			if flag, node = p.memberAccess(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		case 4:
+			if flag, node = p.varType(); flag {
+				state = 5
+				root.AddChild(node)
+			} else {
+				state = 5
+				ok = false
+			}
+		case 5:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap[")"]) {
+				state = 1
+				node = util.NewTreeNode(&token, ")")
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "类型转换缺少 )")
+			}
 		}
 	}
 	return
@@ -1698,7 +2308,7 @@ func (p *Parser) relationalOpe() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少关系运算符")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少关系运算符")
 			}
 		}
 	}
@@ -1725,10 +2335,74 @@ func (p *Parser) boolExp() (ok bool, root *util.TreeNode) {
 			}
 		case 1:
 			if flag, node = p.boolExp0(); flag {
+				state = 2
+				root.AddChild(node)
+			} else {
+				state = 2
+				ok = false
+			}
+		case 2:
+			if flag, node = p.ternaryExp(); flag {
 				state = -1
 				root.AddChild(node)
 			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// ternaryExp <三目表达式>
+func (p *Parser) ternaryExp() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.TERNARY_EXPR
+	root = util.NewTreeNode(nil, nodeName)
+	state := 0
+	var flag bool
+	var node *util.TreeNode
+	var token util.TokenNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["?"]) {
 				state = 1
+				node = util.NewTreeNode(&token, "?")
+				root.AddChild(node)
+			} else {
+				state = -1
+				p.backup()
+				node = util.NewTreeNode(nil, consts.NULL)
+				root.AddChild(node)
+			}
+		case 1:
+			if flag, node = p.boolExp(); flag {
+				state = 2
+				root.AddChild(node)
+			} else {
+				state = 2
+				ok = false
+			}
+		case 2:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap[":"]) {
+				state = 3
+				node = util.NewTreeNode(&token, ":")
+				root.AddChild(node)
+			} else {
+				p.backup()
+				state = 3
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "三目运算缺少 : ")
+			}
+		case 3:
+			if flag, node = p.boolExp(); flag {
+				state = -1
+				root.AddChild(node)
+			} else {
+				state = -1
 				ok = false
 			}
 		}
@@ -1957,7 +2631,7 @@ func (p *Parser) assignmentStatement() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; ")
 			}
 		}
 	}
@@ -1976,13 +2650,35 @@ func (p *Parser) assignmentExp() (ok bool, root *util.TreeNode) {
 	for state != -1 {
 		switch state {
 		case 0:
-			if flag, node = p.Var(); flag {
+			token = p.peek(1)
+			if p.match(token, consts.TokenMap["*"]) { //通过指针赋值
+				p.nextToken()
+				state = 4
+				node = util.NewTreeNode(&token, "*")
+				root.AddChild(node)
+			} else if flag, node = p.Var(); flag {
+				state = 3
+				root.AddChild(node)
+			} else {
+				state = 1
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingIdent, "缺少标识符")
+			}
+		case 4:
+			if flag, node = p.factor(); flag {
+				state = 1
+				root.AddChild(node)
+			} else {
+				state = 1
+				ok = false
+			}
+		case 3:
+			if flag, node = p.memberAccess(); flag {
 				state = 1
 				root.AddChild(node)
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少标识符")
 			}
 		case 1:
 			token = p.nextToken()
@@ -1993,7 +2689,7 @@ func (p *Parser) assignmentExp() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 2
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 = ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 = ")
 			}
 		case 2:
 			if flag, node = p.boolExp(); flag {
@@ -2026,7 +2722,7 @@ func (p *Parser) funcCallStatement() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少函数变量名")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingIdent, "缺少函数变量名")
 			}
 		case 1:
 			if flag, node = p.funcCall(); flag {
@@ -2046,7 +2742,7 @@ func (p *Parser) funcCallStatement() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "函数调用语句缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "函数调用语句缺少 ; ")
 			}
 		}
 	}
@@ -2081,7 +2777,7 @@ func (p *Parser) funcCall() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 2
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ( ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ( ")
 			}
 		case 2:
 			if flag, node = p.actualParamList(); flag {
@@ -2100,13 +2796,18 @@ func (p *Parser) funcCall() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ) ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ) ")
 			}
 		}
 	}
 	return
 }
 
+// isPrefixOperator 判断是否为可以出现在因子前面的单目运算符
+func (p *Parser) isPrefixOperator(token util.TokenNode) bool {
+	return p.match(token, consts.TokenMap["-"]) || p.match(token, consts.TokenMap["+"]) || p.match(token, consts.TokenMap["!"]) || p.match(token, consts.TokenMap["*"]) || p.match(token, consts.TokenMap["&"])
+}
+
 // actualParamList <实参列表>
 func (p *Parser) actualParamList() (ok bool, root *util.TreeNode) {
 	ok = true
@@ -2120,7 +2821,7 @@ func (p *Parser) actualParamList() (ok bool, root *util.TreeNode) {
 		switch state {
 		case 0:
 			token = p.peek(1)
-			if p.isConstType(token) || p.match(token, consts.TokenMap["identifier"]) || p.match(token, consts.TokenMap["("]) {
+			if p.isConstType(token) || p.match(token, consts.TokenMap["identifier"]) || p.match(token, consts.TokenMap["("]) || p.isPrefixOperator(token) || p.match(token, consts.TokenMap["stringer"]) {
 				state = 1
 			} else {
 				state = -1
@@ -2153,7 +2854,9 @@ func (p *Parser) actualParam() (ok bool, root *util.TreeNode) {
 		switch state {
 		case 0:
 			token = p.peek(1)
-			if p.isConstType(token) || p.match(token, consts.TokenMap["identifier"]) || p.match(token, consts.TokenMap["("]) {
+			if p.match(token, consts.TokenMap["stringer"]) { //字符串常量只能作为实参
+				state = 3
+			} else if p.isConstType(token) || p.match(token, consts.TokenMap["identifier"]) || p.match(token, consts.TokenMap["("]) || p.isPrefixOperator(token) {
 				state = 1
 			} else {
 				state = -1
@@ -2176,6 +2879,41 @@ func (p *Parser) actualParam() (ok bool, root *util.TreeNode) {
 				state = -1
 				ok = false
 			}
+		case 3:
+			if flag, node = p.stringConst(); flag {
+				state = 2
+				root.AddChild(node)
+			} else {
+				state = -1
+				ok = false
+			}
+		}
+	}
+	return
+}
+
+// stringConst <字符串常量>
+func (p *Parser) stringConst() (ok bool, root *util.TreeNode) {
+	ok = true
+	nodeName := consts.STRING_CONSTANT
+	root = util.NewTreeNode(nil, nodeName)
+	var token util.TokenNode
+	state := 0
+	var node *util.TreeNode
+	for state != -1 {
+		switch state {
+		case 0:
+			token = p.nextToken()
+			if p.match(token, consts.TokenMap["stringer"]) {
+				state = -1
+				node = util.NewTreeNode(&token, token.Value)
+				root.AddChild(node)
+			} else {
+				p.backup()
+				state = -1
+				ok = false
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingConst, "缺少字符串常量")
+			}
 		}
 	}
 	return
@@ -2455,7 +3193,7 @@ func (p *Parser) controlStatement() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少控制语句关键字")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少控制语句关键字")
 			}
 		case 1:
 			if flag, node = p.IF(); flag {
@@ -2538,7 +3276,7 @@ func (p *Parser) IF() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少if")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少if")
 			}
 		case 1:
 			token = p.nextToken()
@@ -2549,7 +3287,7 @@ func (p *Parser) IF() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 2
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, " if 缺少 ( ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, " if 缺少 ( ")
 			}
 		case 2:
 			if flag, node = p.boolExp(); flag {
@@ -2565,10 +3303,12 @@ func (p *Parser) IF() (ok bool, root *util.TreeNode) {
 				state = 4
 				node = util.NewTreeNode(&token, ")")
 				root.AddChild(node)
+			} else if p.assignInCondition(token, nodeName) {
+				//已经报错并跳过了=右边的表达式，留在当前状态继续匹配条件后面的符号
 			} else {
 				state = 4
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "if 缺少 ) ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "if 缺少 ) ")
 			}
 		case 4:
 			if flag, node = p.compoundStatement(); flag {
@@ -2620,7 +3360,7 @@ func (p *Parser) IfTail() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 2
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少else")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少else")
 			}
 		case 2:
 			if flag, node = p.IfTail0(); flag {
@@ -2655,7 +3395,7 @@ func (p *Parser) IfTail0() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "else 缺少 { 或 if")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "else 缺少 { 或 if")
 			}
 		case 1:
 			if flag, node = p.compoundStatement(); flag {
@@ -2698,7 +3438,7 @@ func (p *Parser) FOR() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少for")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少for")
 			}
 		case 1:
 			token = p.nextToken()
@@ -2709,7 +3449,7 @@ func (p *Parser) FOR() (ok bool, root *util.TreeNode) {
 			} else {
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, " for 缺少 ( ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, " for 缺少 ( ")
 			}
 		case 2:
 			if flag, node = p.assignmentExp(); flag {
@@ -2729,7 +3469,7 @@ func (p *Parser) FOR() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = 4
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; ")
 			}
 		case 4:
 			if flag, node = p.boolExp(); flag {
@@ -2745,11 +3485,13 @@ func (p *Parser) FOR() (ok bool, root *util.TreeNode) {
 				state = 6
 				node = util.NewTreeNode(&token, ";")
 				root.AddChild(node)
+			} else if p.assignInCondition(token, nodeName) {
+				//已经报错并跳过了=右边的表达式，留在当前状态继续匹配条件后面的符号
 			} else {
 				p.backup()
 				state = 6
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ; ")
 			}
 		case 6:
 			if flag, node = p.assignmentExp(); flag {
@@ -2768,7 +3510,7 @@ func (p *Parser) FOR() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 8
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "for 缺少 ) ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "for 缺少 ) ")
 			}
 		case 8:
 			if flag, node = p.compoundStatement(); flag {
@@ -2803,7 +3545,7 @@ func (p *Parser) WHILE() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少while")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少while")
 			}
 		case 1:
 			token = p.nextToken()
@@ -2814,7 +3556,7 @@ func (p *Parser) WHILE() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 2
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, " while 缺少 ( ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, " while 缺少 ( ")
 			}
 		case 2:
 			if flag, node = p.boolExp(); flag {
@@ -2830,10 +3572,12 @@ func (p *Parser) WHILE() (ok bool, root *util.TreeNode) {
 				state = 4
 				node = util.NewTreeNode(&token, ")")
 				root.AddChild(node)
+			} else if p.assignInCondition(token, nodeName) {
+				//已经报错并跳过了=右边的表达式，留在当前状态继续匹配条件后面的符号
 			} else {
 				state = 4
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "while 缺少 ) ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "while 缺少 ) ")
 			}
 		case 4:
 			if flag, node = p.compoundStatement(); flag {
@@ -2868,7 +3612,7 @@ func (p *Parser) DoWHILE() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少do")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少do")
 			}
 		case 1:
 			if flag, node = p.compoundStatement(); flag {
@@ -2887,7 +3631,7 @@ func (p *Parser) DoWHILE() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 3
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, " do 缺少 while")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, " do 缺少 while")
 			}
 		case 3:
 			token = p.nextToken()
@@ -2898,7 +3642,7 @@ func (p *Parser) DoWHILE() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 4
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少 ( ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少 ( ")
 			}
 		case 4:
 			if flag, node = p.boolExp(); flag {
@@ -2914,10 +3658,12 @@ func (p *Parser) DoWHILE() (ok bool, root *util.TreeNode) {
 				state = 6
 				node = util.NewTreeNode(&token, ")")
 				root.AddChild(node)
+			} else if p.assignInCondition(token, nodeName) {
+				//已经报错并跳过了=右边的表达式，留在当前状态继续匹配条件后面的符号
 			} else {
 				state = 6
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "while 缺少 ) ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "while 缺少 ) ")
 			}
 		case 6:
 			token = p.nextToken()
@@ -2929,7 +3675,7 @@ func (p *Parser) DoWHILE() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "do while 缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "do while 缺少 ; ")
 			}
 		}
 	}
@@ -2956,7 +3702,7 @@ func (p *Parser) Return() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少return")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少return")
 			}
 		case 1:
 			if flag, node = p.Return0(); flag {
@@ -3007,7 +3753,7 @@ func (p *Parser) Return0() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "return 缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "return 缺少 ; ")
 			}
 		}
 	}
@@ -3033,7 +3779,7 @@ func (p *Parser) Break() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少break")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少break")
 			}
 		case 1:
 			token = p.nextToken()
@@ -3045,7 +3791,7 @@ func (p *Parser) Break() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "break 缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "break 缺少 ; ")
 			}
 		}
 	}
@@ -3071,7 +3817,7 @@ func (p *Parser) Continue() (ok bool, root *util.TreeNode) {
 			} else {
 				state = 1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "缺少continue")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "缺少continue")
 			}
 		case 1:
 			token = p.nextToken()
@@ -3083,7 +3829,7 @@ func (p *Parser) Continue() (ok bool, root *util.TreeNode) {
 				p.backup()
 				state = -1
 				ok = false
-				p.Logger.AddParserErr(token, nodeName, "continue 缺少 ; ")
+				p.Logger.AddParserErr(token, nodeName, logger.CodeMissingToken, "continue 缺少 ; ")
 			}
 		}
 	}
@@ -0,0 +1,248 @@
+package compiler
+
+import (
+	"bytes"
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 导出的符号种类
+const (
+	KindVariable  = "variable"
+	KindParameter = "parameter"
+	KindConstant  = "constant"
+	KindFunction  = "function"
+	KindStruct    = "struct"
+	KindField     = "field"
+	KindEnum      = "enum"
+)
+
+// SymbolEntry 导出的符号表中的一项
+type SymbolEntry struct {
+	Scope      string       `json:"scope"` //所在函数，全局符号为@all
+	Level      int          `json:"level"` //作用域等级，0表示全局
+	Name       string       `json:"name"`  //源程序中的名字
+	Kind       string       `json:"kind"`
+	Type       string       `json:"type"`
+	Value      string       `json:"value"`                //常量和常量初值的全局变量的值，其他变量为空
+	Params     []string     `json:"params,omitempty"`     //函数的形参类型
+	ParamNames []string     `json:"paramNames,omitempty"` //函数的形参名
+	Decl       *logger.Span `json:"decl,omitempty"`       //声明的位置
+	Refs       *int         `json:"refs,omitempty"`       //被引用的次数
+}
+
+// next 返回下一个符号的声明顺序
+func (s *SymbolTable) next() int {
+	s.seq++
+	return s.seq
+}
+
+// scopes 返回表中的作用域，全局作用域在前，函数作用域按函数加入符号表的顺序排列
+func (s *SymbolTable) scopes(table map[string]map[string]*Info) []string {
+	names := make([]string, 0, len(table))
+	for name := range table {
+		names = append(names, name)
+	}
+	rank := func(name string) int {
+		if name == consts.ALL {
+			return 0
+		}
+		if f, ok := s.FuncTable[name]; ok {
+			return f.order
+		}
+		return s.seq + 1
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := rank(names[i]), rank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// scoped 按作用域和声明顺序返回变量表或常量表中的符号
+func (s *SymbolTable) scoped(table map[string]map[string]*Info) []*Info {
+	var infos []*Info
+	for _, scope := range s.scopes(table) {
+		infos = append(infos, sortedInfos(table[scope])...)
+	}
+	return infos
+}
+
+// funcs 按声明顺序返回用户定义的函数，内置函数不输出
+func (s *SymbolTable) funcs() []*Info {
+	infos := make(map[string]*Info, len(s.FuncTable))
+	for name, f := range s.FuncTable {
+		if !f.builtin {
+			infos[name] = f
+		}
+	}
+	return sortedInfos(infos)
+}
+
+// types 按声明顺序返回结构体类型
+func (s *SymbolTable) types() []*TypeInfo {
+	types := make([]*TypeInfo, 0, len(s.TypeTable))
+	for _, t := range s.TypeTable {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].order < types[j].order })
+	return types
+}
+
+// enums 按声明顺序返回枚举类型
+func (s *SymbolTable) enums() []*EnumInfo {
+	enums := make([]*EnumInfo, 0, len(s.EnumTable))
+	for _, e := range s.EnumTable {
+		enums = append(enums, e)
+	}
+	sort.Slice(enums, func(i, j int) bool { return enums[i].order < enums[j].order })
+	return enums
+}
+
+// sortedInfos 按声明顺序排列一个作用域中的符号，顺序相同时按名字排列
+func sortedInfos(table map[string]*Info) []*Info {
+	infos := make([]*Info, 0, len(table))
+	for _, info := range table {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].order != infos[j].order {
+			return infos[i].order < infos[j].order
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// symbols 依次返回变量、常量和函数，顺序与String相同
+func (s *SymbolTable) symbols() []*Info {
+	infos := s.scoped(s.VarTable)
+	infos = append(infos, s.scoped(s.ConstTable)...)
+	return append(infos, s.funcs()...)
+}
+
+// kindOf 返回符号的种类
+func (s *SymbolTable) kindOf(info *Info) string {
+	switch {
+	case info.ParamFlag:
+		return KindParameter
+	case s.FuncTable[info.Name] == info:
+		return KindFunction
+	case s.ConstTable[info.Scope][info.Name] == info:
+		return KindConstant
+	}
+	return KindVariable
+}
+
+// valueOf 返回变量在源程序中的初值。只有常量初值的全局变量有初值，其他变量的Value是保存初值的临时变量，不导出
+func (s *SymbolTable) valueOf(v *Info) any {
+	if !v.constInit {
+		return nil
+	}
+	return v.Value
+}
+
+// spanOf 返回声明的位置，没有位置信息时返回nil
+func spanOf(tokens ...*util.TokenNode) *logger.Span {
+	for _, token := range tokens {
+		if token != nil {
+			span := logger.SpanOf(token)
+			return &span
+		}
+	}
+	return nil
+}
+
+// Entries 返回整个符号表，依次为变量、常量、函数、结构体和枚举类型，每类的顺序与String相同
+func (s *SymbolTable) Entries() []SymbolEntry {
+	entries := make([]SymbolEntry, 0)
+	value := func(v any) string {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+	refs := func(sym symbol) *int {
+		n := len(sym.references())
+		return &n
+	}
+	for _, v := range s.scoped(s.VarTable) {
+		entries = append(entries, SymbolEntry{
+			Scope: v.Scope, Level: v.Level, Name: sourceName(v.Name), Kind: s.kindOf(v), Type: v.Type,
+			Value: value(s.valueOf(v)), Decl: spanOf(v.token), Refs: refs(v),
+		})
+	}
+	for _, c := range s.scoped(s.ConstTable) {
+		entries = append(entries, SymbolEntry{
+			Scope: c.Scope, Level: c.Level, Name: c.Name, Kind: KindConstant, Type: c.Type,
+			Value: value(c.Value), Decl: spanOf(c.token), Refs: refs(c),
+		})
+	}
+	for _, f := range s.funcs() {
+		names := make([]string, len(f.ParsName))
+		for i, name := range f.ParsName {
+			names[i] = sourceName(name)
+		}
+		entries = append(entries, SymbolEntry{
+			Scope: f.Scope, Level: f.Level, Name: f.Name, Kind: KindFunction, Type: f.Type,
+			Value: value(f.Value), Params: f.Pars, ParamNames: names, Decl: spanOf(f.declToken, f.defToken), Refs: refs(f),
+		})
+	}
+	for _, t := range s.types() {
+		fields := make([]string, len(t.Fields))
+		for i, field := range t.Fields {
+			fields[i] = field.Type + " " + field.Name
+		}
+		entries = append(entries, SymbolEntry{
+			Scope: consts.ALL, Name: t.Name, Kind: KindStruct, Type: KindStruct,
+			Value: strings.Join(fields, "; "), Decl: spanOf(t.token), Refs: refs(t),
+		})
+	}
+	for _, e := range s.enums() {
+		members := make([]string, len(e.Members))
+		for i, name := range e.Members {
+			members[i] = fmt.Sprintf("%s=%d", name, e.Values[i])
+		}
+		entries = append(entries, SymbolEntry{
+			Scope: consts.ALL, Name: e.Name, Kind: KindEnum, Type: consts.TYPEINT,
+			Value: strings.Join(members, "; "), Decl: spanOf(e.token), Refs: refs(e),
+		})
+	}
+	return entries
+}
+
+// JSON 以JSON数组导出整个符号表
+func (s *SymbolTable) JSON() ([]byte, error) {
+	return util.MarshalJSON(s.Entries())
+}
+
+// CSV 以CSV导出整个符号表，第一行为表头，形参类型和形参名用分号分隔，没有位置信息时行号和列号为空
+func (s *SymbolTable) CSV() ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"scope", "level", "name", "kind", "type", "value", "params", "paramNames", "line", "column", "refs"})
+	for _, e := range s.Entries() {
+		line, column, refs := "", "", ""
+		if e.Decl != nil {
+			line, column = strconv.Itoa(e.Decl.Line), strconv.Itoa(e.Decl.Column)
+		}
+		if e.Refs != nil {
+			refs = strconv.Itoa(*e.Refs)
+		}
+		w.Write([]string{
+			e.Scope, strconv.Itoa(e.Level), e.Name, e.Kind, e.Type, e.Value,
+			strings.Join(e.Params, ";"), strings.Join(e.ParamNames, ";"), line, column, refs,
+		})
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
@@ -20,7 +20,16 @@ type Target struct {
 	FuncMap        map[string]map[string]string // 函数参数和局部变量的地址映射
 	FuncParamLen   int                          // 当前函数参数和局部变量的长度
 	FuncParamNum   int                          // 函数形参个数
+	FuncArgLen     int                          // 函数形参占用的字节数，返回时由被调用的函数弹出
 	FuncTempNum    int                          // 函数临时变量个数（包括局部变量以及临时参数）
+	tempTypes      map[string]string            // 临时变量的类型
+	floatConsts    map[string]string            // 浮点常数及其在数据段中的标号
+	floatPool      []string                     // 常数池中的浮点常数，按出现的顺序排列
+	strConsts      map[string]string            // 字符串常量及其在数据段中的标号
+	runtime        map[string]bool              // 程序用到的运行时过程
+	paraFloat      bool                         // 最近一次传递的参数是否为浮点数
+	paraLen        int                          // 当前调用已压入的实参字节数
+	fpu            bool                         // 是否生成了浮点运算的代码
 }
 
 func NewTarget(qf *util.QuaFormList, table *SymbolTable) *Target {
@@ -35,143 +44,271 @@ func NewTarget(qf *util.QuaFormList, table *SymbolTable) *Target {
 
 // GenerateAsmCode 生成目标代码
 func (t *Target) GenerateAsmCode() {
-	t.CurrentFunc = "main"
+	t.inferTempTypes()
+
+	// 先生成代码段，得到程序用到的运行时过程和是否用到8087，再生成数据段
+	start := t.mainIndex()
+	for i := start; i < len(t.Qf.QuaForms); i++ {
+		t.genForm(i, t.Qf.QuaForms[i])
+	}
+	t.genInit(start)
+	t.genRuntime()
+	code := t.Asm.String()
+	t.Asm.Reset()
 
 	// 生成汇编代码头
 	t.Asm.WriteString(consts.ASM_HEAD)
+	t.genRuntimeData()
 
-	// 生成全局变量
-	for funcName, table := range t.SymbolTable.VarTable {
-		if funcName != consts.ALL && funcName != "main" {
+	// 生成全局变量，按作用域和声明的顺序排列，使同一程序每次生成的代码相同
+	for _, info := range t.SymbolTable.scoped(t.SymbolTable.VarTable) {
+		if info.Scope != consts.ALL && info.Scope != "main" {
 			continue
 		}
-		for name, _ := range table {
-			t.Asm.WriteString(fmt.Sprintf("\t_%s dw 0\n", name))
+		if info.Type == consts.TYPEFLOAT { // 浮点变量为单精度浮点数
+			t.Asm.WriteString(fmt.Sprintf("\t_%s dd %s\n", info.Name, initValue(info, "0.0")))
+		} else if size := t.varSize(info); size > 2 { // 结构体变量按大小分配多个字
+			t.Asm.WriteString(fmt.Sprintf("\t_%s dw %d dup (0)\n", info.Name, size/2))
+		} else {
+			t.Asm.WriteString(fmt.Sprintf("\t_%s dw %s\n", info.Name, initValue(info, "0")))
 		}
-
 	}
 
 	// 生成全局常量
-	for funcName, table := range t.SymbolTable.ConstTable {
-		if funcName != consts.ALL && funcName != "main" {
+	for _, info := range t.SymbolTable.scoped(t.SymbolTable.ConstTable) {
+		if info.Scope != consts.ALL && info.Scope != "main" {
 			continue
 		}
-		for name, info := range table {
-			t.Asm.WriteString(fmt.Sprintf("\t_%s dw %s\n", name, info.Value))
+		if info.Type == consts.TYPEFLOAT {
+			t.Asm.WriteString(fmt.Sprintf("\t_%s dd %s\n", info.Name, info.Value))
+		} else {
+			t.Asm.WriteString(fmt.Sprintf("\t_%s dw %s\n", info.Name, info.Value))
 		}
 	}
 
+	// 生成浮点常数和字符串常量
+	t.genFloatData()
+	t.genStringData()
+
 	// 生成汇编代码入口
 	t.Asm.WriteString(consts.ASM_START)
+	if t.usesFPU() {
+		t.Asm.WriteString(consts.ASM_FPU_INIT)
+	}
+	t.Asm.WriteString("\n\n")
+
+	// 全局变量的初始化在启动过程_init中按声明的顺序执行，在main函数之前调用
+	if t.hasInit(start) {
+		t.Asm.WriteString("\tCALL _init\n")
+	}
+	t.Asm.WriteString(code)
+	t.Asm.WriteString(consts.ASM_END)
+}
 
+// popArgs 变参函数不知道实参的个数，由调用者在返回后弹出实参
+func (t *Target) popArgs(name any) string {
+	n := t.paraLen
+	t.paraLen = 0
+	if info, ok := t.SymbolTable.FindFunction(name.(string)); ok && info.variadic && n > 0 {
+		return fmt.Sprintf("\tADD SP,%d\n", n)
+	}
+	return ""
+}
+
+// genForm 生成一条四元式的目标代码
+func (t *Target) genForm(i int, form *util.QuaForm) {
+	op := form.Op
+	arg1 := form.Arg1
+	arg2 := form.Arg2
+	result := form.Result
+
+	if op == "main" {
+		return
+	}
+	if t.isConstInit(form) { // 常量的值已经写入数据段，只保留跳转可能用到的标号
+		t.Asm.WriteString(fmt.Sprintf("_%d:\n", i))
+		return
+	}
+	if t.genFloat(i, op.(string), arg1, arg2, result) {
+		t.fpu = true
+		return
+	}
+
+	switch op {
+	case "=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(result)))
+	case "+":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tADD AX,%s\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
+	case "-", "@":
+		if op == "@" { //求负运算，0-arg
+			arg2 = arg1
+			arg1 = "0"
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tSUB AX,%s\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
+	case "*":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV BX,%s\n\tMUL BX\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
+	case "/":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV DX,0\n\tMOV BX,%s\n\tDIV BX\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
+	case "%":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV DX,0\n\tMOV BX,%s\n\tDIV BX\n\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
+	case "<":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJL _GT_%d\n\tMOV DX,0\n_GT_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
+	case "<=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJLE _LE_%d\n\tMOV DX,0\n_LE_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
+	case ">":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJG _LT_%d\n\tMOV DX,0\n_LT_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
+	case ">=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJGE _GE_%d\n\tMOV DX,0\n_GE_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
+	case "==":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJE _EQ_%d\n\tMOV DX,0\n_EQ_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
+	case "!=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJNE _NE_%d\n\tMOV DX,0\n_NE_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
+	case "j<":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjl _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
+	case "j>=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjge _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
+	case "j>":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjg _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
+	case "j<=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjle _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
+	case "j==":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tje _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
+	case "j!=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjne _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
+	case "&&":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,0\n\tMOV AX,%s\n\tCMP AX,0\n\tJE _AND_%d\n\tMOV AX,%s\n\tCMP AX,0\n\tJE _AND_%d\n\tMOV DX,1\n_AND_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), i, t.DataAdress(arg2), i, i, t.DataAdress(result)))
+	case "||":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,0\n\tJNE _OR_%d\n\tMOV AX,%s\n\tCMP AX,0\n\tJNE _OR_%d\n\tMOV DX,0\n_OR_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), i, t.DataAdress(arg2), i, i, t.DataAdress(result)))
+	case "!":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,0\n\tJE _NOT_%d\n\tMOV DX,0\n_NOT_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), i, i, t.DataAdress(result)))
+	case "jmp":
+		jmp := "_" + strconv.Itoa(result.(int))
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tJMP far ptr %s\n", i, jmp))
+	case "jz":
+		jmp := "_" + strconv.Itoa(result.(int))
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,0\n\tJNE _NE_%d\n\tJMP far ptr %s\n_NE_%d:\tNOP\n", i, t.DataAdress(arg1), i, jmp, i))
+	case "jnz":
+		jmp := "_" + strconv.Itoa(result.(int))
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,0\n\tJE _EZ_%d\n\tJMP far ptr %s\n_EZ_%d:\tNOP\n", i, t.DataAdress(arg1), i, jmp, i))
+	case ".":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV %s,AX\n", i, t.FieldAdress(arg1, arg2), t.DataAdress(result)))
+	case ".=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.FieldAdress(result, arg2)))
+	case "&":
+		addr := t.FieldAdress(arg1, arg2)
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tLEA BX,%s\n", i, addr))
+		if strings.HasPrefix(addr, "ss:") { // 栈上变量的偏移换算为相对数据段的偏移
+			t.Asm.WriteString("\tMOV AX,SS\n\tSUB AX,DS\n\tMOV CL,4\n\tSHL AX,CL\n\tADD BX,AX\n")
+		}
+		t.Asm.WriteString(fmt.Sprintf("\tMOV %s,BX\n", t.DataAdress(result)))
+	case "deref":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV BX,%s\n\tMOV AX,ds:[BX]\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(result)))
+	case "deref=":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV BX,%s\n\tMOV AX,%s\n\tMOV ds:[BX],AX\n", i, t.DataAdress(result), t.DataAdress(arg1)))
+	case "ctoi", "itoc": // 字符只保留低字节，符号扩展为字；整型转换为字符时同样截断为低字节
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCBW\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(result)))
+	case "para":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tPUSH AX\n", i, t.DataAdress(arg1)))
+		t.paraLen += 2
+	case "call":
+		t.useRuntime(arg1.(string))
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tCALL %s\n%s", i, arg1, t.popArgs(arg1)))
+		if result != nil { // 函数调用有返回值
+			t.Asm.WriteString(fmt.Sprintf("\tMOV %s,AX\n", t.DataAdress(result)))
+		}
+	case "ret":
+		if result != nil { // 函数返回有返回值
+			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV SP,BP\n\tPOP BP\n\t%s\n", i, t.DataAdress(result), t.retInstr()))
+		} else {
+			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV SP,BP\n\tPOP BP\n\t%s\n", i, t.retInstr()))
+		}
+	case "sys":
+		if result != nil { // 退出码在AL中
+			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV AH,4Ch\n\tINT 21h\n", i, t.DataAdress(result)))
+		} else {
+			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AH,4Ch\n\tINT 21h\n", i))
+		}
+	default: // 函数定义，局部变量和临时变量都在函数自己的栈帧中，四元式之间不在寄存器中保留值，递归调用时只需保存BP
+		t.CurrentFunc = op.(string)
+		t.CurrentId = i + 1
+		t.getFuncParamLen()
+		t.Asm.WriteString(fmt.Sprintf("%s:\tPUSH BP\n\tMOV BP,SP\n\tSUB SP,%d\n", op, t.FuncParamLen))
+	}
+}
+
+// initValue 获取全局变量在数据段中的初值，只有初值为常量的全局变量直接写入，字符常数写成其编码
+func initValue(info *Info, zero string) string {
+	if !info.constInit {
+		return zero
+	}
+	value := fmt.Sprint(info.Value)
+	if code, ok := charCode(value); ok {
+		return strconv.Itoa(code)
+	}
+	return value
+}
+
+// mainIndex 获取main函数的四元式索引，在它之前的四元式是全局变量和常量的初始化
+func (t *Target) mainIndex() int {
 	for i, form := range t.Qf.QuaForms {
-		op := form.Op
-		arg1 := form.Arg1
-		arg2 := form.Arg2
-		result := form.Result
+		if form.Op == "main" {
+			return i
+		}
+	}
+	return 0
+}
 
-		if op == "main" {
-			continue
+// genInit 生成全局变量初始化的启动过程，初值为常量的全局变量和全局常量已经直接写入数据段
+func (t *Target) genInit(start int) {
+	if !t.hasInit(start) {
+		return
+	}
+	t.CurrentFunc = "main" //初始化代码与main函数一样使用数据段和扩展段
+	t.Asm.WriteString("_init:\n")
+	for i := 0; i < start; i++ {
+		if !t.isConstInit(t.Qf.QuaForms[i]) {
+			t.genForm(i, t.Qf.QuaForms[i])
 		}
+	}
+	t.Asm.WriteString("\tRET\n")
+}
 
-		switch op {
-		case "=":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(result)))
-		case "+":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tADD AX,%s\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
-		case "-", "@":
-			if op == "@" { //求负运算，0-arg
-				arg2 = arg1
-				arg1 = "0"
-			}
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tSUB AX,%s\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
-		case "*":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV BX,%s\n\tMUL BX\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
-		case "/":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV DX,0\n\tMOV BX,%s\n\tDIV BX\n\tMOV %s,AX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
-		case "%":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV DX,0\n\tMOV BX,%s\n\tDIV BX\n\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), t.DataAdress(result)))
-		case "<":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJL _GT_%d\n\tMOV DX,0\n_GT_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
-		case "<=":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJLE _LE_%d\n\tMOV DX,0\n_LE_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
-		case ">":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJG _LT_%d\n\tMOV DX,0\n_LT_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
-		case ">=":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJGE _GE_%d\n\tMOV DX,0\n_GE_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
-		case "==":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJE _EQ_%d\n\tMOV DX,0\n_EQ_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
-		case "!=":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,%s\n\tJNE _NE_%d\n\tMOV DX,0\n_NE_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), t.DataAdress(arg2), i, i, t.DataAdress(result)))
-		case "j<":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjl _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
-		case "j>=":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjge _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
-		case "j>":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjg _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
-		case "j<=":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjle _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
-		case "j==":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tje _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
-		case "j!=":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,%s\n\tjne _%d\n", i, t.DataAdress(arg1), t.DataAdress(arg2), result))
-		case "&&":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,0\n\tMOV AX,%s\n\tCMP AX,0\n\tJE _AND_%d\n\tMOV AX,%s\n\tCMP AX,0\n\tJE _AND_%d\n\tMOV DX,1\n_AND_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), i, t.DataAdress(arg2), i, i, t.DataAdress(result)))
-		case "||":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,0\n\tJNE _OR_%d\n\tMOV AX,%s\n\tCMP AX,0\n\tJNE _OR_%d\n\tMOV DX,0\n_OR_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), i, t.DataAdress(arg2), i, i, t.DataAdress(result)))
-		case "!":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tMOV AX,%s\n\tCMP AX,0\n\tJE _NOT_%d\n\tMOV DX,0\n_NOT_%d:\tMOV %s,DX\n", i, t.DataAdress(arg1), i, i, t.DataAdress(result)))
-		case "jmp":
-			jmp := "_" + strconv.Itoa(result.(int))
-			// 跳转的位置为程序结束时，跳转到退出程序的位置
-			if t.Qf.GetQuaForm(result.(int)).Op == "sys" {
-				jmp = "quit"
-			}
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tJMP far ptr %s\n", i, jmp))
-		case "jz":
-			jmp := "_" + strconv.Itoa(result.(int))
-			// 跳转的位置为程序结束时，跳转到退出程序的位置
-			if t.Qf.GetQuaForm(result.(int)).Op == "sys" {
-				jmp = "quit"
-			}
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,0\n\tJNE _NE_%d\n\tJMP far ptr %s\n_NE_%d:\tNOP\n", i, t.DataAdress(arg1), i, jmp, i))
-		case "jnz":
-			jmp := "_" + strconv.Itoa(result.(int))
-			// 跳转的位置为程序结束时，跳转到退出程序的位置
-			if t.Qf.GetQuaForm(result.(int)).Op == "sys" {
-				jmp = "quit"
-			}
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tCMP AX,0\n\tJE _EZ_%d\n\tJMP far ptr %s\n_EZ_%d:\tNOP\n", i, t.DataAdress(arg1), i, jmp, i))
-		case "para":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tPUSH AX\n", i, t.DataAdress(arg1)))
-		case "call":
-			t.Asm.WriteString(fmt.Sprintf("_%d:\tCALL %s\n", i, arg1))
-			if result != nil { // 函数调用有返回值
-				t.Asm.WriteString(fmt.Sprintf("\tMOV %s,AX\n", t.DataAdress(result)))
-			}
-		case "ret":
-			if result != nil { // 函数返回有返回值
-				t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV SP,BP\n\tPOP BP\n\tRET\n", i, t.DataAdress(result)))
-			} else {
-				t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV SP,BP\n\tPOP BP\n\tRET\n", i))
+// hasInit 判断main函数之前是否有需要在_init中执行的初始化
+func (t *Target) hasInit(start int) bool {
+	for i := 0; i < start; i++ {
+		if !t.isConstInit(t.Qf.QuaForms[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// dataConst 查找数据段中的常量，全局常量和main函数的常量都在数据段中
+func (t *Target) dataConst(name string) (*Info, bool) {
+	for _, scope := range []string{consts.ALL, "main"} {
+		for _, info := range t.SymbolTable.ConstTable[scope] {
+			if info.Name == name && info.Scope == scope {
+				return info, true
 			}
-		case "sys":
-			t.Asm.WriteString("quit:\tMOV AH,4Ch\n\tINT 21h\n")
-		default: // 函数定义
-			t.CurrentFunc = op.(string)
-			t.CurrentId = i + 1
-			t.getFuncParamLen()
-			t.Asm.WriteString(fmt.Sprintf("%s:\tPUSH BP\n\tMOV BP,SP\n\tSUB SP,%d\n", op, t.FuncParamLen))
 		}
 	}
+	return nil, false
+}
 
-	t.Asm.WriteString(consts.ASM_END)
+// isConstInit 判断四元式是否为数据段中常量的赋值，这些常量的值已经写入数据段，不需要生成代码
+func (t *Target) isConstInit(form *util.QuaForm) bool {
+	name, ok := form.Result.(string)
+	if !ok || form.Op != "=" {
+		return false
+	}
+	_, ok = t.dataConst(name)
+	return ok
 }
 
 // isFuncDef 判断当前四元式是否为函数定义
 func (t *Target) isFuncDef(op any) bool {
 	ope := op.(string)
-	if ope == "=" || ope == "+" || ope == "-" || ope == "*" || ope == "/" || ope == "%" || ope == "<" || ope == "<=" || ope == ">" || ope == ">=" || ope == "==" || ope == "!=" || ope == "j<" || ope == "j>=" || ope == "j>" || ope == "j<=" || ope == "j==" || ope == "j!=" || ope == "&&" || ope == "||" || ope == "!" || ope == "jmp" || ope == "jz" || ope == "jnz" || ope == "para" || ope == "call" || ope == "ret" || ope == "sys" || ope == "@" || ope == "#" {
+	if ope == "=" || ope == "+" || ope == "-" || ope == "*" || ope == "/" || ope == "%" || ope == "<" || ope == "<=" || ope == ">" || ope == ">=" || ope == "==" || ope == "!=" || ope == "j<" || ope == "j>=" || ope == "j>" || ope == "j<=" || ope == "j==" || ope == "j!=" || ope == "&&" || ope == "||" || ope == "!" || ope == "jmp" || ope == "jz" || ope == "jnz" || ope == "para" || ope == "call" || ope == "ret" || ope == "sys" || ope == "@" || ope == "#" || ope == "." || ope == ".=" || ope == "&" || ope == "deref" || ope == "deref=" || ope == "itof" || ope == "ftoi" || ope == "ctoi" || ope == "itoc" {
 		return false
 	}
 	return true
@@ -198,9 +335,13 @@ func (t *Target) setFuncParamAddr(param any) {
 			//t.FuncParamLen += 2
 			t.FuncMap[t.CurrentFunc][p] = fmt.Sprintf("ss:[bp+%d]", 4+t.FuncParamNum*2) // 函数形参地址, 从bp+4开始,bp+2为返回地址,bp+0为bp
 			t.FuncParamNum++
-		} else if !t.isDigit(p) { // 非常量数字，是局部变量或临时变量
-			t.FuncParamLen += 2
-			t.FuncMap[t.CurrentFunc][p] = fmt.Sprintf("ss:[bp-%d]", 2+t.FuncTempNum*2) // 局部变量地址, 从bp-2开始
+		} else if !t.isDigit(p) && !t.isFloatLiteral(p) { // 非常量数字，是局部变量或临时变量
+			if p[0] == '$' && t.tempTypes[p] == consts.TYPEFLOAT {
+				t.FuncParamLen += 4
+			} else {
+				t.FuncParamLen += t.varSize(t.SymbolTable.VarTable[t.CurrentFunc][p])
+			}
+			t.FuncMap[t.CurrentFunc][p] = fmt.Sprintf("ss:[bp-%d]", t.FuncParamLen) // 局部变量地址, 从bp-2开始，结构体变量占用连续的多个字
 			t.FuncTempNum++
 		}
 	}
@@ -208,11 +349,22 @@ func (t *Target) setFuncParamAddr(param any) {
 
 // initFuncParamAddr 初始化函数的形参对应的地址
 func (t *Target) initFuncParamAddr() {
+	offset := 4
 	for _, name := range t.SymbolTable.FuncTable[t.CurrentFunc].ParsName {
 		//t.FuncParamLen += 2
-		t.FuncMap[t.CurrentFunc][name] = fmt.Sprintf("ss:[bp+%d]", 4+t.FuncParamNum*2) // 函数形参地址, 从bp+4开始,bp+2为返回地址,bp+0为bp
+		t.FuncMap[t.CurrentFunc][name] = fmt.Sprintf("ss:[bp+%d]", offset) // 函数形参地址, 从bp+4开始,bp+2为返回地址,bp+0为bp
+		offset += t.varSize(t.SymbolTable.VarTable[t.CurrentFunc][name])   // 浮点形参占两个字
 		t.FuncParamNum++
 	}
+	t.FuncArgLen = offset - 4
+}
+
+// retInstr 函数返回指令，与read、write一样由被调用的函数弹出实参，调用者不需要再调整SP
+func (t *Target) retInstr() string {
+	if t.FuncArgLen == 0 {
+		return "RET"
+	}
+	return fmt.Sprintf("RET %d", t.FuncArgLen)
 }
 
 // getFuncParamLen 获取函数形参、局部变量及临时变量的长度
@@ -259,9 +411,17 @@ func (t *Target) DataAdress(arg any) string {
 	}
 	param := arg.(string)
 	p := ""
+	if isStringLiteral(param) { // 字符串常量，取其在数据段中的偏移
+		return "offset " + t.strConsts[param]
+	}
+	if code, ok := charCode(param); ok { // 字符常数，直接取其编码
+		return strconv.Itoa(code)
+	}
 	if t.CurrentFunc == "main" { // main函数，从数据段中取值
-		if param[0] == '$' { // 临时变量，从扩展段的栈中取值
-			p = fmt.Sprintf("es:[%d]", t.toInt(param[2:])*2)
+		if param[0] == '$' { // 临时变量，从扩展段的栈中取值，每个临时变量占两个字以便存放浮点数
+			p = fmt.Sprintf("es:[%d]", t.toInt(param[2:])*4)
+		} else if t.isFloatLiteral(param) { // 浮点常数，从数据段的常数池中取值
+			p = fmt.Sprintf("ds:[%s]", t.floatConsts[param])
 		} else if t.isDigit(param) { // 数字，直接取值
 			p = param
 		} else { // 变量，从数据段中取值
@@ -270,6 +430,8 @@ func (t *Target) DataAdress(arg any) string {
 	} else { // 当前函数不是main函数，从栈中取值
 		if t.isDigit(param) { // 数字，直接取值
 			p = param
+		} else if t.isFloatLiteral(param) { // 浮点常数，从数据段的常数池中取值
+			p = fmt.Sprintf("ds:[%s]", t.floatConsts[param])
 		} else if t.isGlobalVar(param) { // 全局变量或全局常量
 			p = fmt.Sprintf("ds:[_%s]", param)
 		} else { // 当前函数形参以及局部变量
@@ -279,6 +441,23 @@ func (t *Target) DataAdress(arg any) string {
 	return p
 }
 
+// FieldAdress 获取结构体成员地址，在结构体变量地址的基础上加上成员偏移
+func (t *Target) FieldAdress(arg any, offset any) string {
+	off, _ := offset.(int)
+	return wordAt(t.DataAdress(arg), off)
+}
+
+// varSize 获取变量占用的字节数，临时变量和整型变量占一个字，浮点变量占两个字
+func (t *Target) varSize(info *Info) int {
+	if info == nil {
+		return 2
+	}
+	if size, ok := t.SymbolTable.TypeSize(info.Type); ok && size > 2 {
+		return size
+	}
+	return 2
+}
+
 func (t *Target) toInt(s string) int {
 	i, _ := strconv.Atoi(s)
 	return i
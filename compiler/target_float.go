@@ -0,0 +1,299 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// floatJmp 浮点比较后FCOMP的结果存放在标志位中，与无符号数比较使用相同的条件跳转
+var floatJmp = map[string]string{
+	"<":  "JB",
+	"<=": "JBE",
+	">":  "JA",
+	">=": "JAE",
+	"==": "JE",
+	"!=": "JNE",
+}
+
+// floatArith 浮点四则运算对应的8087指令
+var floatArith = map[string]string{
+	"+": "FADD",
+	"-": "FSUB",
+	"*": "FMUL",
+	"/": "FDIV",
+}
+
+// fpuStatus 将8087的状态字送入标志寄存器
+const fpuStatus = "\tFSTSW ds:[_fsw]\n\tFWAIT\n\tMOV AX,ds:[_fsw]\n\tSAHF\n"
+
+// inferTempTypes 按四元式的顺序推导每个临时变量的类型，浮点型临时变量需要占用两个字
+func (t *Target) inferTempTypes() {
+	t.tempTypes = make(map[string]string)
+	t.floatConsts = make(map[string]string)
+	t.floatPool = nil
+	t.strConsts = make(map[string]string)
+	t.CurrentFunc = "main"
+	constLabels := make(map[string]string) //数据段中浮点常量的值->常量的标号，值相同的浮点常数直接使用常量
+	for _, info := range t.SymbolTable.scoped(t.SymbolTable.ConstTable) {
+		value := fmt.Sprint(info.Value)
+		if _, ok := constLabels[value]; !ok && info.Type == consts.TYPEFLOAT && (info.Scope == consts.ALL || info.Scope == "main") {
+			constLabels[value] = "_" + info.Name
+		}
+	}
+	for _, form := range t.Qf.QuaForms {
+		op := form.Op.(string)
+		if op != "main" && t.isFuncDef(op) {
+			t.CurrentFunc = op
+			continue
+		}
+		if t.isConstInit(form) { //常量的值已经写入数据段
+			continue
+		}
+		for _, arg := range []any{form.Arg1, form.Arg2, form.Result} { //ret的返回值放在Result中
+			if s, ok := arg.(string); ok && t.isFloatLiteral(s) {
+				if _, ok = t.floatConsts[s]; ok {
+					continue
+				}
+				if label, ok := constLabels[s]; ok {
+					t.floatConsts[s] = label
+				} else {
+					t.floatConsts[s] = fmt.Sprintf("_fc%d", len(t.floatPool))
+					t.floatPool = append(t.floatPool, s)
+				}
+			} else if ok && isStringLiteral(s) {
+				if _, ok = t.strConsts[s]; !ok {
+					t.strConsts[s] = fmt.Sprintf("_s%d", len(t.strConsts))
+				}
+			}
+		}
+		result, ok := form.Result.(string)
+		if !ok || !strings.HasPrefix(result, "$") {
+			continue
+		}
+		switch op {
+		case "itof":
+			t.tempTypes[result] = consts.TYPEFLOAT
+		case "+", "-", "*", "/", "@", "#":
+			if t.argType(form.Arg1) == consts.TYPEFLOAT || t.argType(form.Arg2) == consts.TYPEFLOAT {
+				t.tempTypes[result] = consts.TYPEFLOAT
+			} else if isPointer(t.argType(form.Arg1)) {
+				t.tempTypes[result] = t.argType(form.Arg1)
+			}
+		case "=":
+			t.tempTypes[result] = t.argType(form.Arg1)
+		case ".":
+			t.tempTypes[result] = t.fieldType(t.argType(form.Arg1), form.Arg2)
+		case "&":
+			t.tempTypes[result] = t.fieldType(t.argType(form.Arg1), form.Arg2) + "*"
+		case "deref":
+			t.tempTypes[result] = elemType(t.argType(form.Arg1))
+		case "call":
+			if info, ok := t.SymbolTable.FindFunction(form.Arg1.(string)); ok {
+				t.tempTypes[result] = info.Type
+			}
+		}
+	}
+	t.CurrentFunc = "main"
+}
+
+// argType 获取四元式中操作数的类型
+func (t *Target) argType(arg any) string {
+	param, ok := arg.(string)
+	if !ok || param == "" {
+		return ""
+	}
+	if param[0] == '$' {
+		return t.tempTypes[param]
+	}
+	if t.isFloatLiteral(param) {
+		return consts.TYPEFLOAT
+	}
+	if t.isDigit(param) {
+		return consts.TYPEINT
+	}
+	if info, ok := t.SymbolTable.FindVariable(t.CurrentFunc, param); ok {
+		return info.Type
+	}
+	if info, ok := t.SymbolTable.FindConstant(t.CurrentFunc, param); ok {
+		return info.Type
+	}
+	return ""
+}
+
+// fieldType 根据偏移查找结构体中最内层的成员类型，偏移为nil时返回结构体类型本身
+func (t *Target) fieldType(structType string, offset any) string {
+	off, ok := offset.(int)
+	if !ok {
+		return structType
+	}
+	for {
+		info, ok := t.SymbolTable.FindType(structType)
+		if !ok {
+			return structType
+		}
+		found := false
+		for _, field := range info.Fields {
+			if off >= field.Offset && off < field.Offset+field.Size {
+				structType, off, found = field.Type, off-field.Offset, true
+				break
+			}
+		}
+		if !found {
+			return ""
+		}
+	}
+}
+
+// testZero 将操作数与0比较，相等时置ZF。浮点数与0.0比较，不能只比较低位字
+func (t *Target) testZero(arg any) string {
+	if t.isFloat(arg) {
+		return fmt.Sprintf("\tFLDZ\n\tFCOMP dword ptr %s\n%s", t.DataAdress(arg), fpuStatus)
+	}
+	return fmt.Sprintf("\tMOV AX,%s\n\tCMP AX,0\n", t.DataAdress(arg))
+}
+
+// isFloat 判断操作数是否为浮点型
+func (t *Target) isFloat(arg any) bool {
+	return t.argType(arg) == consts.TYPEFLOAT
+}
+
+// isFloatLiteral 判断是否为浮点常数
+func (t *Target) isFloatLiteral(s string) bool {
+	if !strings.Contains(s, ".") {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 32)
+	return err == nil
+}
+
+// wordAt 在地址的基础上加上偏移，用于访问结构体成员或浮点数的高位字
+func wordAt(addr string, off int) string {
+	if off == 0 {
+		return addr
+	}
+	return fmt.Sprintf("%s+%d]", addr[:len(addr)-1], off)
+}
+
+// genFloatData 生成浮点常数池
+func (t *Target) genFloatData() {
+	for i, value := range t.floatPool { //按常数出现的顺序生成
+		t.Asm.WriteString(fmt.Sprintf("\t_fc%d dd %s\n", i, value))
+	}
+}
+
+// genFloat 生成浮点运算的目标代码，四元式不涉及浮点数时返回false，由整数运算的代码生成处理
+func (t *Target) genFloat(i int, op string, arg1, arg2, result any) bool {
+	switch op {
+	case "=":
+		if !t.isFloat(arg1) && !t.isFloat(result) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tFLD dword ptr %s\n\tFSTP dword ptr %s\n", i, t.DataAdress(arg1), t.DataAdress(result)))
+	case "+", "-", "*", "/":
+		if !t.isFloat(arg1) && !t.isFloat(arg2) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tFLD dword ptr %s\n\t%s dword ptr %s\n\tFSTP dword ptr %s\n", i, t.DataAdress(arg1), floatArith[op], t.DataAdress(arg2), t.DataAdress(result)))
+	case "@":
+		if !t.isFloat(arg1) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tFLD dword ptr %s\n\tFCHS\n\tFSTP dword ptr %s\n", i, t.DataAdress(arg1), t.DataAdress(result)))
+	case "<", "<=", ">", ">=", "==", "!=":
+		if !t.isFloat(arg1) && !t.isFloat(arg2) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n\tFLD dword ptr %s\n\tFCOMP dword ptr %s\n", i, t.DataAdress(arg1), t.DataAdress(arg2)))
+		t.Asm.WriteString(fpuStatus)
+		t.Asm.WriteString(fmt.Sprintf("\t%s _FC_%d\n\tMOV DX,0\n_FC_%d:\tMOV %s,DX\n", floatJmp[op], i, i, t.DataAdress(result)))
+	case "j<", "j<=", "j>", "j>=", "j==", "j!=":
+		if !t.isFloat(arg1) && !t.isFloat(arg2) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tFLD dword ptr %s\n\tFCOMP dword ptr %s\n", i, t.DataAdress(arg1), t.DataAdress(arg2)))
+		t.Asm.WriteString(fpuStatus)
+		t.Asm.WriteString(fmt.Sprintf("\t%s _%d\n", strings.ToLower(floatJmp[op[1:]]), result))
+	case "!":
+		if !t.isFloat(arg1) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n%s\tJE _NOT_%d\n\tMOV DX,0\n_NOT_%d:\tMOV %s,DX\n", i, t.testZero(arg1), i, i, t.DataAdress(result)))
+	case "&&":
+		if !t.isFloat(arg1) && !t.isFloat(arg2) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,0\n%s\tJE _AND_%d\n%s\tJE _AND_%d\n\tMOV DX,1\n_AND_%d:\tMOV %s,DX\n", i, t.testZero(arg1), i, t.testZero(arg2), i, i, t.DataAdress(result)))
+	case "||":
+		if !t.isFloat(arg1) && !t.isFloat(arg2) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV DX,1\n%s\tJNE _OR_%d\n%s\tJNE _OR_%d\n\tMOV DX,0\n_OR_%d:\tMOV %s,DX\n", i, t.testZero(arg1), i, t.testZero(arg2), i, i, t.DataAdress(result)))
+	case "jz":
+		if !t.isFloat(arg1) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\n%s\tJNE _NE_%d\n\tJMP far ptr _%d\n_NE_%d:\tNOP\n", i, t.testZero(arg1), i, result, i))
+	case "jnz":
+		if !t.isFloat(arg1) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\n%s\tJE _EZ_%d\n\tJMP far ptr _%d\n_EZ_%d:\tNOP\n", i, t.testZero(arg1), i, result, i))
+	case "itof":
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tFILD word ptr %s\n\tFSTP dword ptr %s\n", i, t.DataAdress(arg1), t.DataAdress(result)))
+	case "ftoi": // C语言的浮点数转整数为截断，转换时临时切换8087的舍入方式
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tFLD dword ptr %s\n\tFLDCW ds:[_fcw_trunc]\n\tFISTP word ptr %s\n\tFLDCW ds:[_fcw_near]\n", i, t.DataAdress(arg1), t.DataAdress(result)))
+	case ".":
+		if !t.isFloat(result) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tFLD dword ptr %s\n\tFSTP dword ptr %s\n", i, t.FieldAdress(arg1, arg2), t.DataAdress(result)))
+	case ".=":
+		if t.fieldType(t.argType(result), arg2) != consts.TYPEFLOAT {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tFLD dword ptr %s\n\tFSTP dword ptr %s\n", i, t.DataAdress(arg1), t.FieldAdress(result, arg2)))
+	case "deref":
+		if !t.isFloat(result) {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV BX,%s\n\tFLD dword ptr ds:[BX]\n\tFSTP dword ptr %s\n", i, t.DataAdress(arg1), t.DataAdress(result)))
+	case "deref=":
+		if elemType(t.argType(result)) != consts.TYPEFLOAT {
+			return false
+		}
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV BX,%s\n\tFLD dword ptr %s\n\tFSTP dword ptr ds:[BX]\n", i, t.DataAdress(result), t.DataAdress(arg1)))
+	case "para": // 浮点参数占两个字，先压入高位字
+		t.paraFloat = t.isFloat(arg1)
+		if !t.paraFloat {
+			return false
+		}
+		addr := t.DataAdress(arg1)
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tPUSH AX\n\tMOV AX,%s\n\tPUSH AX\n", i, wordAt(addr, 2), addr))
+		t.paraLen += 4
+	case "call":
+		if arg1 == "write" && t.paraFloat { // 输出浮点数使用writef
+			t.paraFloat = false
+			t.paraLen = 0
+			t.useRuntime("writef")
+			t.Asm.WriteString(fmt.Sprintf("_%d:\tCALL writef\n", i))
+			return true
+		}
+		t.paraFloat = false
+		if result == nil || !t.isFloat(result) {
+			return false
+		}
+		addr := t.DataAdress(result)
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tCALL %s\n%s\tMOV %s,AX\n\tMOV %s,DX\n", i, arg1, t.popArgs(arg1), addr, wordAt(addr, 2)))
+	case "ret": // 浮点返回值的低位字在AX，高位字在DX
+		if result == nil || !t.isFloat(result) {
+			return false
+		}
+		addr := t.DataAdress(result)
+		t.Asm.WriteString(fmt.Sprintf("_%d:\tMOV AX,%s\n\tMOV DX,%s\n\tMOV SP,BP\n\tPOP BP\n\t%s\n", i, addr, wordAt(addr, 2), t.retInstr()))
+	default:
+		return false
+	}
+	return true
+}
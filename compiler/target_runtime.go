@@ -0,0 +1,109 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isStringLiteral 判断是否为字符串常量
+func isStringLiteral(s string) bool {
+	return len(s) >= 2 && s[0] == '"'
+}
+
+// charCode 求字符常数的编码，支持转义字符
+func charCode(s string) (int, bool) {
+	if len(s) < 3 || s[0] != '\'' {
+		return 0, false
+	}
+	r, _, _, err := strconv.UnquoteChar(s[1:len(s)-1], '\'')
+	if err != nil {
+		return 0, false
+	}
+	return int(r), true
+}
+
+// genStringData 生成字符串常量池，字符串以0结尾
+func (t *Target) genStringData() {
+	values := make([]string, len(t.strConsts))
+	for value, label := range t.strConsts {
+		values[t.toInt(label[2:])] = value
+	}
+	for i, value := range values { //按字符串出现的顺序生成
+		t.Asm.WriteString(fmt.Sprintf("\t_s%d db %s\n", i, asmString(value)))
+	}
+}
+
+// asmString 将字符串常量转换为db伪指令的操作数，可显示字符放在引号内，其余字符使用十六进制
+func asmString(literal string) string {
+	s, err := strconv.Unquote(literal)
+	if err != nil {
+		s = strings.Trim(literal, "\"")
+	}
+	var parts []string
+	quoted := ""
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= ' ' && c <= '~' && c != '\'' {
+			quoted += string(c)
+			continue
+		}
+		if quoted != "" {
+			parts = append(parts, "'"+quoted+"'")
+			quoted = ""
+		}
+		parts = append(parts, fmt.Sprintf("0%02xh", c))
+	}
+	if quoted != "" {
+		parts = append(parts, "'"+quoted+"'")
+	}
+	return strings.Join(append(parts, "0"), ",")
+}
+
+// useRuntime 记录程序调用的内置函数，只生成用到的运行时过程
+func (t *Target) useRuntime(name string) {
+	if t.runtime == nil {
+		t.runtime = make(map[string]bool)
+	}
+	t.runtime[name] = true
+}
+
+// genRuntime 生成程序用到的运行时过程
+func (t *Target) genRuntime() {
+	print := false
+	for _, proc := range runtimeProcs {
+		if t.runtime[proc.Name] {
+			t.Asm.WriteString(proc.Code)
+			print = print || proc.Print
+		}
+	}
+	if print {
+		t.Asm.WriteString(consts.ASM_PRINT)
+	}
+}
+
+// genRuntimeData 生成程序用到的运行时过程和8087所需的数据
+func (t *Target) genRuntimeData() {
+	for _, proc := range runtimeProcs {
+		if t.runtime[proc.Name] {
+			t.Asm.WriteString(proc.Data)
+		}
+	}
+	if t.usesFPU() {
+		t.Asm.WriteString(consts.ASM_FPU_DATA)
+	}
+}
+
+// usesFPU 判断程序是否用到8087，包括浮点运算和输出浮点数的运行时过程
+func (t *Target) usesFPU() bool {
+	if t.fpu {
+		return true
+	}
+	for _, proc := range runtimeProcs {
+		if t.runtime[proc.Name] && proc.FPU {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,359 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/pkg/logger"
+	"complier/util"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 警告名，用于-W<名字>/-Wno-<名字>开关和源程序中的nowarn注释
+const (
+	WarnUnusedVariable      = "unused-variable"      // 局部变量未使用
+	WarnUnusedParameter     = "unused-parameter"     // 形参未使用
+	WarnUnusedFunction      = "unused-function"      // 函数定义后未被调用
+	WarnShadow              = "shadow"               // 局部变量遮蔽了外层的同名符号
+	WarnNarrowing           = "narrowing"            // 隐式转换可能丢失精度
+	WarnTautologicalCompare = "tautological-compare" // 比较结果恒为true或false
+	WarnEnumRange           = "enum-range"           // 枚举值超出范围
+	WarnUninitialized       = "uninitialized"        // 读取时变量在所有路径上都没有赋值
+	WarnMaybeUninitialized  = "maybe-uninitialized"  // 读取时变量在某些路径上没有赋值
+	WarnUnreachableCode     = "unreachable-code"     // return、break、continue之后不可达的语句
+	WarnDivByZero           = "div-by-zero"          // 除数一定为0的除法和取模
+	WarnOverflow            = "overflow"             // 结果超出16位整数范围的整型运算
+)
+
+// defaultWarnings 所有警告及其默认是否开启
+var defaultWarnings = map[string]bool{
+	WarnUnusedVariable:      true,
+	WarnUnusedParameter:     false,
+	WarnUnusedFunction:      true,
+	WarnShadow:              false,
+	WarnNarrowing:           true,
+	WarnTautologicalCompare: true,
+	WarnEnumRange:           true,
+	WarnUninitialized:       true,
+	WarnMaybeUninitialized:  true,
+	WarnUnreachableCode:     true,
+	WarnDivByZero:           true,
+	WarnOverflow:            true,
+}
+
+// WarningOptions 警告选项
+type WarningOptions struct {
+	enabled map[string]bool //警告名->是否开启
+	errors  map[string]bool //作为错误报告的警告
+	werror  bool            //所有警告都作为错误报告
+}
+
+// NewWarningOptions 创建默认的警告选项
+func NewWarningOptions() *WarningOptions {
+	w := &WarningOptions{enabled: make(map[string]bool), errors: make(map[string]bool)}
+	for name, on := range defaultWarnings {
+		w.enabled[name] = on
+	}
+	for _, r := range defaultLintRules() { //lint规则是否运行由配置文件决定，这里只用于-Wno-<规则名>关闭其报告
+		w.enabled[r.rule.Name()] = true
+	}
+	return w
+}
+
+// knownWarning 判断name是否是警告名或lint规则名
+func knownWarning(name string) bool {
+	if _, ok := defaultWarnings[name]; ok {
+		return true
+	}
+	for _, r := range defaultLintRules() {
+		if r.rule.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Set 设置一个警告选项，支持-Wall、-W<名字>、-Wno-<名字>、-Werror、-Wno-error和-Werror=<名字>，名字也可以是lint规则名
+func (w *WarningOptions) Set(flag string) error {
+	name, ok := strings.CutPrefix(flag, "-W")
+	if !ok {
+		return fmt.Errorf("未知的警告选项: %s", flag)
+	}
+	on := true
+	if rest, ok := strings.CutPrefix(name, "no-"); ok {
+		name, on = rest, false
+	}
+	switch {
+	case name == "all":
+		for n := range w.enabled {
+			w.enabled[n] = on
+		}
+	case name == "error":
+		w.werror = on
+	case strings.HasPrefix(name, "error="):
+		name = strings.TrimPrefix(name, "error=")
+		if !knownWarning(name) || !on {
+			return fmt.Errorf("未知的警告选项: %s", flag)
+		}
+		w.enabled[name] = true
+		w.errors[name] = true
+	default:
+		if !knownWarning(name) {
+			return fmt.Errorf("未知的警告选项: %s", flag)
+		}
+		w.enabled[name] = on
+	}
+	return nil
+}
+
+// Parse 按顺序设置以空白分隔的多个警告选项，后面的选项覆盖前面的
+func (w *WarningOptions) Parse(flags string) error {
+	for _, flag := range strings.Fields(flags) {
+		if err := w.Set(flag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enabled 判断警告是否开启
+func (w *WarningOptions) Enabled(name string) bool {
+	return w.enabled[name]
+}
+
+// IsError 判断警告是否作为错误报告
+func (w *WarningOptions) IsError(name string) bool {
+	return w.werror || w.errors[name]
+}
+
+// SetComments 读取源程序注释中的nowarn指令，"// nowarn"关闭注释所在行的所有警告，注释单独占一行时还关闭下一行的警告，
+// "// nowarn: unused-variable, shadow"只关闭列出的警告
+func (a *Analyser) SetComments(comments []util.TokenNode) {
+	a.suppressed = make(map[int][]string)
+	code := make(map[int]bool) //含有代码的行
+	codeLines(a.Ast, code)
+	for _, c := range comments {
+		text := strings.TrimSpace(strings.TrimSuffix(strings.TrimLeft(c.Value, "/*"), "*/"))
+		rest, ok := strings.CutPrefix(text, "nowarn")
+		if !ok {
+			continue
+		}
+		names := []string{""}
+		if rest, ok = strings.CutPrefix(strings.TrimSpace(rest), ":"); ok {
+			names = strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		} else if rest != "" { //nowarn后面只能是冒号或者什么都没有
+			continue
+		}
+		last := c.Pos.Line + strings.Count(c.Value, "\n")
+		if !code[c.Pos.Line] && !code[last] { //行尾的注释只作用于所在的行
+			last++
+		}
+		for line := c.Pos.Line; line <= last; line++ {
+			a.suppressed[line] = append(a.suppressed[line], names...)
+		}
+	}
+}
+
+// codeLines 记录语法树中的token所在的行
+func codeLines(node *util.TreeNode, lines map[int]bool) {
+	if node == nil {
+		return
+	}
+	if node.Token != nil {
+		lines[node.Token.Pos.Line] = true
+	}
+	for _, child := range node.Children {
+		codeLines(child, lines)
+	}
+}
+
+// isSuppressed 判断某一行的警告是否被nowarn注释关闭
+func (a *Analyser) isSuppressed(name string, line int) bool {
+	for _, n := range a.suppressed[line] {
+		if n == "" || n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// warn 报告一个警告，code为诊断码，警告关闭或被注释关闭时忽略并返回nil，-Werror时作为错误报告
+func (a *Analyser) warn(name string, token *util.TokenNode, code string, msg ...string) *logger.Diagnostic {
+	if a.isSuppressed(name, token.Pos.Line) {
+		return nil
+	}
+	return a.Warnings.report(a.Logger, name, token, code, msg...)
+}
+
+// report 按警告选项向l报告名为name的警告，警告关闭时返回nil
+func (w *WarningOptions) report(l *logger.Logger, name string, token *util.TokenNode, code string, msg ...string) *logger.Diagnostic {
+	if !w.Enabled(name) {
+		return nil
+	}
+	if w.IsError(name) {
+		d := l.AddAnalyseErr(token, code, msg...)
+		d.Option = "-Werror=" + name
+		if w.werror {
+			d.Option = "-Werror"
+		}
+		return d
+	}
+	d := l.AddAnalyseWarn(token, code, msg...)
+	d.Option = "-W" + name
+	return d
+}
+
+// checkShadow 检查局部变量是否遮蔽了外层块或全局的同名变量、常量
+func (a *Analyser) checkShadow(name string, token *util.TokenNode) {
+	if a.Scope == consts.ALL || token == nil {
+		return
+	}
+	if outer, ok := a.SymbolTable.FindVariable(a.Scope, name); ok {
+		if d := a.warn(WarnShadow, token, logger.CodeShadow, "变量遮蔽了外层的同名变量: ", name); d != nil {
+			d.WithRelated(outer.token, "外层的变量位于此处")
+		}
+		return
+	}
+	if _, ok := a.SymbolTable.ConstTable[consts.ALL][name]; ok {
+		a.warn(WarnShadow, token, logger.CodeShadow, "变量遮蔽了全局常量: ", name)
+	}
+}
+
+// checkUnused 分析结束后检查未使用的局部变量、形参和函数
+func (a *Analyser) checkUnused() {
+	var vars []*Info
+	for scope, table := range a.SymbolTable.VarTable {
+		if scope == consts.ALL {
+			continue
+		}
+		for _, v := range table {
+			if len(v.uses) == 0 && v.token != nil {
+				vars = append(vars, v)
+			}
+		}
+	}
+	var funcs []*Info
+	called := make(map[string]bool)
+	for _, call := range a.calls {
+		if call.name.Value != call.caller { //只在自身中递归调用的函数仍然未使用
+			called[call.name.Value] = true
+		}
+	}
+	for _, f := range a.SymbolTable.FuncTable {
+		if f.funcFlag && !f.builtin && f.Name != "main" && !called[f.Name] && f.defToken != nil {
+			funcs = append(funcs, f)
+		}
+	}
+	sort.Slice(vars, func(i, j int) bool { return before(vars[i].token, vars[j].token) })
+	sort.Slice(funcs, func(i, j int) bool { return before(funcs[i].defToken, funcs[j].defToken) })
+	for _, v := range vars {
+		if v.ParamFlag {
+			a.warn(WarnUnusedParameter, v.token, logger.CodeUnusedParam, "形参未使用: ", sourceName(v.Name))
+		} else {
+			a.warn(WarnUnusedVariable, v.token, logger.CodeUnusedVar, "变量未使用: ", sourceName(v.Name))
+		}
+	}
+	for _, f := range funcs {
+		a.warn(WarnUnusedFunction, f.defToken, logger.CodeUnusedFunc, "函数未使用: ", f.Name)
+	}
+}
+
+// before 判断token t1在源程序中是否位于t2之前
+func before(t1, t2 *util.TokenNode) bool {
+	p, q := t1.Pos, t2.Pos
+	return p.Line < q.Line || p.Line == q.Line && p.Column < q.Column
+}
+
+// typeLimits 比较时取值范围有限的类型
+var typeLimits = map[string][2]int{
+	consts.TYPECHAR: {-128, 127},
+	consts.TYPEBOOL: {0, 1},
+}
+
+// checkComparison 检查结果恒为true或false的关系运算: 变量与自身比较，
+// 以及char、bool型的操作数与超出其取值范围的常数比较
+func (a *Analyser) checkComparison(node *util.TreeNode) {
+	rela := node.Children[1]
+	op := rela.Children[0].Children[0].Value
+	left, right := node.Children[0], rela.Children[1]
+	result, ok := a.sameOperand(left, right, op)
+	if !ok {
+		result, ok = a.outOfRange(left, op, right)
+	}
+	if !ok {
+		result, ok = a.outOfRange(right, flipRelation(op), left)
+	}
+	if ok {
+		a.warn(WarnTautologicalCompare, firstToken(node), logger.CodeTautologicalCompare, fmt.Sprintf("比较结果恒为%t", result))
+	}
+}
+
+// sameOperand 两个操作数是同一个整型变量时比较的结果
+func (a *Analyser) sameOperand(left, right *util.TreeNode, op string) (bool, bool) {
+	f1, f2 := singleFactor(left), singleFactor(right)
+	if f1 == nil || f2 == nil || memberAccessOf(f1, 0) != nil || memberAccessOf(f2, 0) != nil {
+		return false, false
+	}
+	v1, v2 := f1.Children[0], f2.Children[0]
+	if v1.Value != consts.VARIABLE || v2.Value != consts.VARIABLE || v1.Children[0].Value != v2.Children[0].Value {
+		return false, false
+	}
+	if t := a.exprType(left); t == "" || t == consts.TYPEFLOAT { //浮点数与自身比较时要考虑NaN
+		return false, false
+	}
+	return op == "==" || op == "<=" || op == ">=", true
+}
+
+// outOfRange 操作数x的类型取值范围有限，与超出范围的常数c比较时 x op c 的结果
+func (a *Analyser) outOfRange(x *util.TreeNode, op string, c *util.TreeNode) (bool, bool) {
+	limits, ok := typeLimits[a.exprType(x)]
+	if !ok {
+		return false, false
+	}
+	if _, err := a.evalConst(x); err == nil { //两边都是常数时不检查
+		return false, false
+	}
+	n, ok := a.constIntValue(c)
+	if !ok {
+		return false, false
+	}
+	lo, hi := limits[0], limits[1]
+	switch op {
+	case "==", "!=":
+		if n < lo || n > hi {
+			return op == "!=", true
+		}
+	case "<":
+		if n <= lo || n > hi {
+			return n > hi, true
+		}
+	case "<=":
+		if n < lo || n >= hi {
+			return n >= hi, true
+		}
+	case ">":
+		if n < lo || n >= hi {
+			return n < lo, true
+		}
+	case ">=":
+		if n <= lo || n > hi {
+			return n <= lo, true
+		}
+	}
+	return false, false
+}
+
+// flipRelation 交换关系运算两个操作数时对应的运算符
+func flipRelation(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
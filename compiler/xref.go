@@ -0,0 +1,228 @@
+package compiler
+
+import (
+	"complier/pkg/consts"
+	"complier/util"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Xref 交叉引用索引，记录每个标识符的使用解析到的符号，符号的所有使用按顺序保存在符号的uses中。
+// 索引的符号包括变量、常量、函数、结构体类型、结构体成员和枚举类型
+type Xref struct {
+	table *SymbolTable
+	uses  map[util.Position]symbol //引用的位置->解析到的符号
+}
+
+// symbol 可以查找声明和引用的符号
+type symbol interface {
+	declarations() []*util.TokenNode
+	definition() *util.TokenNode
+	references() []*util.TokenNode
+	addUse(token *util.TokenNode)
+}
+
+func newXref(table *SymbolTable) *Xref {
+	return &Xref{table: table, uses: make(map[util.Position]symbol)}
+}
+
+// add 记录一次对符号的引用，同一位置的标识符可能被分析多次，只记录一次
+func (x *Xref) add(sym symbol, token *util.TokenNode) {
+	if token == nil {
+		return
+	}
+	if _, ok := x.uses[token.Pos]; ok {
+		return
+	}
+	x.uses[token.Pos] = sym
+	sym.addUse(token)
+}
+
+// addTypeUses 记录语法树中所有<变量类型>里结构体和枚举类型名的引用。
+// 类型名在结构体自身的成员中就可能被引用，此时类型还没有加入符号表，所以在分析结束后统一记录
+func (x *Xref) addTypeUses(node *util.TreeNode) {
+	if node == nil {
+		return
+	}
+	if node.Value == consts.VARIABLE_TYPE && len(node.Children) > 0 {
+		name := node.Children[0]
+		if t, ok := x.table.FindType(name.Value); ok {
+			x.add(t, name.Token)
+		} else if e, ok := x.table.FindEnum(name.Value); ok {
+			x.add(e, name.Token)
+		}
+		return
+	}
+	for _, child := range node.Children {
+		x.addTypeUses(child)
+	}
+}
+
+// declarations 返回符号声明的位置，函数依次为声明和定义中函数名的位置
+func (i *Info) declarations() []*util.TokenNode {
+	var tokens []*util.TokenNode
+	for _, token := range []*util.TokenNode{i.token, i.declToken, i.defToken} {
+		if token != nil && (len(tokens) == 0 || tokens[len(tokens)-1] != token) {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// definition 返回符号定义的位置，函数为函数定义中函数名的位置，没有定义时为声明的位置
+func (i *Info) definition() *util.TokenNode {
+	if i.defToken != nil {
+		return i.defToken
+	}
+	if i.token != nil {
+		return i.token
+	}
+	return i.declToken
+}
+
+// references 返回符号被引用的位置
+func (i *Info) references() []*util.TokenNode { return i.uses }
+
+// addUse 记录符号被引用的位置
+func (i *Info) addUse(token *util.TokenNode) { i.uses = append(i.uses, token) }
+
+// 结构体类型、结构体成员和枚举类型只有一处声明，也就是它的定义
+func (t *TypeInfo) declarations() []*util.TokenNode { return tokensOf(t.token) }
+
+func (t *TypeInfo) definition() *util.TokenNode { return t.token }
+
+func (t *TypeInfo) references() []*util.TokenNode { return t.uses }
+
+func (t *TypeInfo) addUse(token *util.TokenNode) { t.uses = append(t.uses, token) }
+
+func (f *Field) declarations() []*util.TokenNode { return tokensOf(f.token) }
+
+func (f *Field) definition() *util.TokenNode { return f.token }
+
+func (f *Field) references() []*util.TokenNode { return f.uses }
+
+func (f *Field) addUse(token *util.TokenNode) { f.uses = append(f.uses, token) }
+
+func (e *EnumInfo) declarations() []*util.TokenNode { return tokensOf(e.token) }
+
+func (e *EnumInfo) definition() *util.TokenNode { return e.token }
+
+func (e *EnumInfo) references() []*util.TokenNode { return e.uses }
+
+func (e *EnumInfo) addUse(token *util.TokenNode) { e.uses = append(e.uses, token) }
+
+// tokensOf 返回只包含token的切片，token为nil时返回nil
+func tokensOf(token *util.TokenNode) []*util.TokenNode {
+	if token == nil {
+		return nil
+	}
+	return []*util.TokenNode{token}
+}
+
+// Symbol 返回位置pos处的标识符解析到的变量、常量或函数，pos可以是符号的引用，也可以是符号的声明
+func (x *Xref) Symbol(pos util.Position) (*Info, bool) {
+	info, ok := x.lookup(pos).(*Info)
+	return info, ok
+}
+
+// lookup 返回位置pos处的标识符解析到的符号，包括结构体类型、结构体成员和枚举类型，找不到时返回nil
+func (x *Xref) lookup(pos util.Position) symbol {
+	if sym, ok := x.uses[pos]; ok {
+		return sym
+	}
+	for _, sym := range x.declared() {
+		for _, token := range sym.declarations() {
+			if token.Pos == pos {
+				return sym
+			}
+		}
+	}
+	return nil
+}
+
+// declared 按符号表的顺序返回所有符号，结构体类型后面紧跟它的成员
+func (x *Xref) declared() []symbol {
+	var syms []symbol
+	for _, info := range x.table.symbols() {
+		syms = append(syms, info)
+	}
+	for _, t := range x.table.types() {
+		syms = append(syms, t)
+		for _, field := range t.Fields {
+			syms = append(syms, field)
+		}
+	}
+	for _, e := range x.table.enums() {
+		syms = append(syms, e)
+	}
+	return syms
+}
+
+// Definition 返回位置pos处的标识符所指符号的定义位置
+func (x *Xref) Definition(pos util.Position) (*util.TokenNode, bool) {
+	sym := x.lookup(pos)
+	if sym == nil || sym.definition() == nil {
+		return nil, false
+	}
+	return sym.definition(), true
+}
+
+// References 返回位置pos处的标识符所指符号的所有引用，按在源程序中的位置排列
+func (x *Xref) References(pos util.Position) []*util.TokenNode {
+	sym := x.lookup(pos)
+	if sym == nil {
+		return nil
+	}
+	return sortedTokens(sym.references())
+}
+
+// Occurrences 返回位置pos处的标识符所指符号的声明、定义和所有引用，重命名时需要修改这些位置
+func (x *Xref) Occurrences(pos util.Position) []*util.TokenNode {
+	sym := x.lookup(pos)
+	if sym == nil {
+		return nil
+	}
+	return sortedTokens(append(sym.declarations(), sym.references()...))
+}
+
+// Report 返回交叉引用报告，每个符号一行，顺序与符号表相同，结构体成员的名字写作类型名.成员名
+func (x *Xref) Report() string {
+	str := "交叉引用: \n作用域\t\t符号名\t种类\t\t定义\t\t引用\n"
+	line := func(scope string, name string, kind string, sym symbol) {
+		def := "-"
+		if token := sym.definition(); token != nil {
+			def = posString(token)
+		}
+		refs := make([]string, 0, len(sym.references()))
+		for _, token := range sortedTokens(sym.references()) {
+			refs = append(refs, posString(token))
+		}
+		str += fmt.Sprintf("%s\t\t%s\t%s\t%s\t\t%s\n", scope, name, kind, def, strings.Join(refs, " "))
+	}
+	for _, info := range x.table.symbols() {
+		line(info.Scope, sourceName(info.Name), x.table.kindOf(info), info)
+	}
+	for _, t := range x.table.types() {
+		line(consts.ALL, t.Name, KindStruct, t)
+		for _, field := range t.Fields {
+			line(consts.ALL, t.Name+"."+field.Name, KindField, field)
+		}
+	}
+	for _, e := range x.table.enums() {
+		line(consts.ALL, e.Name, KindEnum, e)
+	}
+	return str
+}
+
+// sortedTokens 按在源程序中的位置排列token
+func sortedTokens(tokens []*util.TokenNode) []*util.TokenNode {
+	sorted := append([]*util.TokenNode(nil), tokens...)
+	sort.Slice(sorted, func(i, j int) bool { return before(sorted[i], sorted[j]) })
+	return sorted
+}
+
+// posString 返回token的位置，格式为行:列
+func posString(token *util.TokenNode) string {
+	return fmt.Sprintf("%d:%d", token.Pos.Line, token.Pos.Column)
+}
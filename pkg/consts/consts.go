@@ -9,9 +9,11 @@ const (
 	TYPEINT   = "int"
 	TYPEFLOAT = "float"
 	TYPECHAR  = "char"
+	TYPEBOOL  = "bool"
 	TYPEFUNC  = "func"
 	TYPECONST = "const"
 	TYPEVAR   = "var"
+	TYPESTR   = "string" //字符串常量的类型，只能作为内置函数的实参
 )
 
 // 关键字
@@ -36,6 +38,9 @@ const (
 	IF
 	ELSE
 	FOR
+	BOOL
+	STRUCT
+	ENUM
 )
 
 // 界符
@@ -93,6 +98,8 @@ const (
 	OREQUAL
 	EVALUATION
 	DOT
+	QUESTION
+	COLON
 )
 
 // 注释符
@@ -123,6 +130,9 @@ var TokenMap = map[string]Token{
 	"if":       IF,
 	"else":     ELSE,
 	"for":      FOR,
+	"bool":     BOOL,
+	"struct":   STRUCT,
+	"enum":     ENUM,
 	//界符
 	"{": LEFTBRACE,
 	"}": RIGHTBRACE,
@@ -170,6 +180,8 @@ var TokenMap = map[string]Token{
 	"|=": OREQUAL,
 	"=":  EVALUATION,
 	".":  DOT,
+	"?":  QUESTION,
+	":":  COLON,
 	//注释
 	"//":   SINGLECOMMENT,
 	"/**/": MULTICOMMENT,
@@ -177,6 +189,7 @@ var TokenMap = map[string]Token{
 
 const (
 	PROGRAM              string = "<程序>"
+	MAIN_TYPE            string = "<主函数类型>"
 	DECLARATION          string = "<声明语句>"
 	VALUE_DECLARATION    string = "<值声明>"
 	CONST_DECLARATION    string = "<常量声明>"
@@ -189,6 +202,8 @@ const (
 	CONSTANT             string = "<常量>"
 	NUM_CONSTANT         string = "<数值型常量>"
 	CHAR_CONSTANT        string = "<字符型常量>"
+	BOOL_CONSTANT        string = "<布尔型常量>"
+	STRING_CONSTANT      string = "<字符串常量>"
 	VARIABLE_DECL        string = "<变量声明>"
 	VARIABLE_TYPE        string = "<变量类型>"
 	VARIABLE_TABLE       string = "<变量声明表>"
@@ -245,6 +260,16 @@ const (
 	FACTOR_0             string = "<因子0>"
 	RELATION_EXPR        string = "<关系表达式>"
 	RELATION_OPERATOR    string = "<关系运算符>"
+	TERNARY_EXPR         string = "<三目表达式>"
+	STRUCT_DECL          string = "<结构体声明>"
+	STRUCT_MEMBERS       string = "<结构体成员表>"
+	STRUCT_MEMBERS_0     string = "<结构体成员表0>"
+	ENUM_DECL            string = "<枚举声明>"
+	ENUM_MEMBERS         string = "<枚举成员表>"
+	ENUM_MEMBERS_0       string = "<枚举成员表0>"
+	ENUM_VALUE           string = "<枚举值>"
+	MEMBER_ACCESS        string = "<成员访问>"
+	POINTER              string = "<指针>"
 )
 
 // 四元式操作符
@@ -292,6 +317,15 @@ const (
 	QUA_SYS                             //标识main函数结束
 	QUA_MOVE                            //标识逻辑运算出口栈需要转移
 	QUA_NORELA                          //无关系运算符
+	QUA_FIELD                           //读取结构体成员
+	QUA_FIELDSET                        //写入结构体成员
+	QUA_ADDR                            //取地址
+	QUA_DEREF                           //读取指针所指的值
+	QUA_DEREFSET                        //写入指针所指的单元
+	QUA_ITOF                            //整型转换为浮点型
+	QUA_FTOI                            //浮点型转换为整型
+	QUA_CTOI                            //字符型转换为整型
+	QUA_ITOC                            //整型转换为字符型，只保留低字节
 )
 
 var QuaFormMap = map[int]string{
@@ -338,14 +372,39 @@ var QuaFormMap = map[int]string{
 	QUA_SYS:               "sys",
 	QUA_MOVE:              "move",
 	QUA_NORELA:            "norela",
+	QUA_FIELD:             ".",
+	QUA_FIELDSET:          ".=",
+	QUA_ADDR:              "&",
+	QUA_DEREF:             "deref",
+	QUA_DEREFSET:          "deref=",
+	QUA_ITOF:              "itof",
+	QUA_FTOI:              "ftoi",
+	QUA_CTOI:              "ctoi",
+	QUA_ITOC:              "itoc",
 }
 
 // 汇编代码头
 const (
 	// 汇编代码头
-	ASM_HEAD = "assume cs:code,ds:data,ss:stack,es:extended\n\nextended segment\n\tdb 1024 dup (0)\nextended ends\n\nstack segment\n\tdb 1024 dup (0)\nstack ends\n\ndispmsg macro message\n    lea dx, message\n    mov ah, 9\n    int 21h\nendm\n\ndata segment\n\t_buff_p db 256 dup (24h)\n\t_buff_s db 256 dup (0)\n\t_msg_p db 0ah,'Output:',0\n\t_msg_s db 0ah,'Input:',0\n    next_row db 0dh,0ah,'$'\n    error db 'input error, please re-enter: ','$'\n"
+	ASM_HEAD = "assume cs:code,ds:data,ss:stack,es:extended\n\nextended segment\n\tdb 2048 dup (0)\nextended ends\n\ndispmsg macro message\n    lea dx, message\n    mov ah, 9\n    int 21h\nendm\n\ndata segment\n\t_buff_p db 256 dup (24h)\n\t_msg_p db 0ah,'Output:',0\n    next_row db 0dh,0ah,'$'\n    error db 'input error, please re-enter: ','$'\n"
 	// 入口
-	ASM_START = "data ends\n\ncode segment\nstart:\tmov ax,extended\n\tmov es,ax\n\tmov ax,stack\n\tmov ss,ax\n\tmov sp,1024\n\tmov bp,sp\n\tmov ax,data\n\tmov ds,ax\n\n\n"
+	ASM_START = "data ends\n\nstack segment\n\tdb 4096 dup (0)\nstack ends\n\ncode segment\nstart:\tmov ax,extended\n\tmov es,ax\n\tmov ax,stack\n\tmov ss,ax\n\tmov sp,4096\n\tmov bp,sp\n\tmov ax,data\n\tmov ds,ax\n"
+	// 8087的状态字、控制字和输出浮点数用的临时单元，程序用到浮点运算时才生成
+	ASM_FPU_DATA = "\t_fsw dw 0\n\t_fint dw 0\n\t_ften dw 10\n\t_fcw_trunc dw 0F7Fh\n\t_fcw_near dw 037Fh\n"
+	// 初始化8087，程序用到浮点运算时才生成
+	ASM_FPU_INIT = "\tfinit\n"
+	// 运行时库，只生成程序中用到的过程
+	ASM_READ_DATA = "\t_msg_s db 0ah,'Input:',0\n"
+	ASM_READ      = "read proc near\n    push bp\n    mov bp, sp\n    mov bx,offset _msg_s\n\tcall _print\n    push bx\n    push cx\n    push dx\nproc_pre_start:\n    xor ax, ax\n    xor bx, bx\n    xor cx, cx\n    xor dx, dx\nproc_judge_sign:\n    mov ah, 1\n    int 21h\n    cmp al, '-'\n    jne proc_next\n    mov dx, 0ffffh\n    jmp proc_digit_in\nproc_next:\n    cmp al, 30h\n    jb proc_unexpected\n    cmp al, 39h\n    ja proc_unexpected\n    sub al, 30h\n    shl bx, 1\n    mov cx, bx\n    shl bx, 1\n    shl bx, 1\n    add bx, cx\n    add bl, al\n    adc bh, 0\nproc_digit_in:\n    mov ah, 1\n    int 21h\n    jmp proc_next\n\nproc_save:\n    cmp dx, 0ffffh\n    jne proc_result_save\n    neg bx\nproc_result_save:\n    mov ax, bx\n    jmp proc_input_done\n\nproc_unexpected:\n    cmp al, 0dh\n    je proc_save\n    dispmsg next_row\n    dispmsg error\n    jmp proc_pre_start\n\nproc_input_done:\n    pop dx\n    pop cx\n    pop bx\n    pop bp\n    ret\nread endp\n\n"
+	ASM_WRITE     = "write proc near\n    push bp\n    mov bp, sp\n    push ax\n    push bx\n    push cx\n    push dx\n    mov bx,offset _msg_p\n\tcall _print\n    xor cx, cx\n    mov bx, [bp+4]\n    test bx, 8000h\n    jz proc_nonneg\n    neg bx\n    mov dl,'-'\n    mov ah, 2\n    int 21h\nproc_nonneg:\n    mov ax, bx\n    cwd\n    mov bx, 10\nproc_div_again:\n    xor dx, dx\n    div bx\n    add dl, 30h\n    push dX\n    inc cx\n    cmp ax, 0\n    jne proc_div_again\nproc_digit_out:\n    pop dx\n    mov ah, 2\n    int 21h\n    loop proc_digit_out\nproc_output_done:\n    pop dx\n    pop cx\n    pop bx\n    pop ax\n    pop bp\n    ret 2\nwrite endp\n\n"
+	ASM_WRITEF    = "writef proc near\n    push bp\n    mov bp, sp\n    push ax\n    push bx\n    push cx\n    push dx\n    mov bx,offset _msg_p\n\tcall _print\n    fld dword ptr [bp+4]\n    ftst\n    fstsw _fsw\n    fwait\n    mov ax, _fsw\n    sahf\n    jae fproc_nonneg\n    fchs\n    mov dl,'-'\n    mov ah, 2\n    int 21h\nfproc_nonneg:\n    fld st(0)\n    fldcw _fcw_trunc\n    fistp _fint\n    fldcw _fcw_near\n    fild _fint\n    fsubp st(1), st(0)\n    mov ax, _fint\n    xor cx, cx\n    mov bx, 10\nfproc_div_again:\n    xor dx, dx\n    div bx\n    add dl, 30h\n    push dx\n    inc cx\n    cmp ax, 0\n    jne fproc_div_again\nfproc_digit_out:\n    pop dx\n    mov ah, 2\n    int 21h\n    loop fproc_digit_out\n    mov dl, '.'\n    mov ah, 2\n    int 21h\n    mov cx, 4\nfproc_frac_out:\n    fimul _ften\n    fld st(0)\n    fldcw _fcw_trunc\n    fistp _fint\n    fldcw _fcw_near\n    fild _fint\n    fsubp st(1), st(0)\n    mov dx, _fint\n    add dl, 30h\n    mov ah, 2\n    int 21h\n    loop fproc_frac_out\n    fstp st(0)\n    pop dx\n    pop cx\n    pop bx\n    pop ax\n    pop bp\n    ret 4\nwritef endp\n\n"
+	ASM_WRITEC    = "writec proc near\n    push bp\n    mov bp, sp\n    mov dl, [bp+4]\n    mov ah, 2\n    int 21h\n    pop bp\n    ret 2\nwritec endp\n\n"
+	ASM_WRITELN   = "writeln proc near\n    dispmsg next_row\n    ret\nwriteln endp\n\n"
+	ASM_PRINTS    = "prints proc near\n    push bp\n    mov bp, sp\n    mov bx, [bp+4]\n    cmp byte ptr [bx], 0\n    je prints_done\n    call _print\nprints_done:\n    pop bp\n    ret 2\nprints endp\n\n"
+	// 按格式串输出，格式串的偏移在[bp+4]，其余实参依次在其后，由调用者弹出实参
+	ASM_PRINTF = "printf proc near\n    push bp\n    mov bp, sp\n    mov si, [bp+4]\n    lea di, [bp+6]\nprintf_next:\n    mov dl, ds:[si]\n    cmp dl, 0\n    je printf_done\n    inc si\n    cmp dl, '%'\n    jne printf_char\n    mov dl, ds:[si]\n    cmp dl, 0\n    je printf_done\n    inc si\n    cmp dl, 'd'\n    je printf_int\n    cmp dl, 'c'\n    je printf_c\n    cmp dl, 'f'\n    je printf_float\n    cmp dl, 's'\n    je printf_str\nprintf_char:\n    mov ah, 2\n    int 21h\n    jmp printf_next\nprintf_c:\n    mov dl, ss:[di]\n    add di, 2\n    jmp printf_char\nprintf_int:\n    mov ax, ss:[di]\n    add di, 2\n    call _putint\n    jmp printf_next\nprintf_float:\n    fld dword ptr ss:[di]\n    add di, 4\n    call _putfloat\n    jmp printf_next\nprintf_str:\n    mov bx, ss:[di]\n    add di, 2\nprintf_str_lp:\n    mov dl, ds:[bx]\n    cmp dl, 0\n    je printf_next\n    mov ah, 2\n    int 21h\n    inc bx\n    jmp printf_str_lp\nprintf_done:\n    pop bp\n    ret\nprintf endp\n\n_putint:\n    xor cx, cx\n    test ax, 8000h\n    jz _pi_div_init\n    push ax\n    mov dl, '-'\n    mov ah, 2\n    int 21h\n    pop ax\n    neg ax\n_pi_div_init:\n    mov bx, 10\n_pi_div:\n    xor dx, dx\n    div bx\n    add dl, 30h\n    push dx\n    inc cx\n    cmp ax, 0\n    jne _pi_div\n_pi_out:\n    pop dx\n    mov ah, 2\n    int 21h\n    loop _pi_out\n    ret\n\n_putfloat:\n    ftst\n    fstsw _fsw\n    fwait\n    mov ax, _fsw\n    sahf\n    jae _pf_nonneg\n    fchs\n    mov dl, '-'\n    mov ah, 2\n    int 21h\n_pf_nonneg:\n    fld st(0)\n    fldcw _fcw_trunc\n    fistp _fint\n    fldcw _fcw_near\n    fild _fint\n    fsubp st(1), st(0)\n    mov ax, _fint\n    call _putint\n    mov dl, '.'\n    mov ah, 2\n    int 21h\n    mov cx, 4\n_pf_frac:\n    fimul _ften\n    fld st(0)\n    fldcw _fcw_trunc\n    fistp _fint\n    fldcw _fcw_near\n    fild _fint\n    fsubp st(1), st(0)\n    mov dx, _fint\n    add dl, 30h\n    mov ah, 2\n    int 21h\n    loop _pf_frac\n    fstp st(0)\n    ret\n\n"
+	// 输出以0结尾的字符串，bx为字符串的偏移
+	ASM_PRINT = "_print:\tmov si,0\n\tmov di,offset _buff_p\n_p_lp_1:\tmov al,ds:[bx+si]\n\tcmp al,0\n\tje _p_brk_1\n\tmov ds:[di],al\n\tinc si\n\tinc di\n\tjmp short _p_lp_1\n_p_brk_1:\tmov dx,offset _buff_p\n\tmov ah,09h\n\tint 21h\n\tmov cx,si\n\tmov di,offset _buff_p\n_p_lp_2:\tmov al,24h\n\tmov ds:[di],al\n\tinc di\n\tloop _p_lp_2\n\tret\n"
 	// 汇编代码尾
-	ASM_END = "read proc near\n    push bp\n    mov bp, sp\n    mov bx,offset _msg_s\n\tcall _print\n    push bx\n    push cx\n    push dx\nproc_pre_start:\n    xor ax, ax\n    xor bx, bx\n    xor cx, cx\n    xor dx, dx\nproc_judge_sign:\n    mov ah, 1\n    int 21h\n    cmp al, '-'\n    jne proc_next\n    mov dx, 0ffffh\n    jmp proc_digit_in\nproc_next:\n    cmp al, 30h\n    jb proc_unexpected\n    cmp al, 39h\n    ja proc_unexpected\n    sub al, 30h\n    shl bx, 1\n    mov cx, bx\n    shl bx, 1\n    shl bx, 1\n    add bx, cx\n    add bl, al\n    adc bh, 0\nproc_digit_in:\n    mov ah, 1\n    int 21h\n    jmp proc_next\n\nproc_save:\n    cmp dx, 0ffffh\n    jne proc_result_save\n    neg bx\nproc_result_save:\n    mov ax, bx\n    jmp proc_input_done\n\nproc_unexpected:\n    cmp al, 0dh\n    je proc_save\n    dispmsg next_row\n    dispmsg error\n    jmp proc_pre_start\n\nproc_input_done:\n    pop dx\n    pop cx\n    pop bx\n    pop bp\n    ret\nread endp\n\nwrite proc near\n    push bp\n    mov bp, sp\n    push ax\n    push bx\n    push cx\n    push dx\n    mov bx,offset _msg_p\n\tcall _print\n    xor cx, cx\n    mov bx, [bp+4]\n    test bx, 8000h\n    jz proc_nonneg\n    neg bx\n    mov dl,'-'\n    mov ah, 2\n    int 21h\nproc_nonneg:\n    mov ax, bx\n    cwd\n    mov bx, 10\nproc_div_again:\n    xor dx, dx\n    div bx\n    add dl, 30h\n    push dX\n    inc cx\n    cmp ax, 0\n    jne proc_div_again\nproc_digit_out:\n    pop dx\n    mov ah, 2\n    int 21h\n    loop proc_digit_out\nproc_output_done:\n    pop dx\n    pop cx\n    pop bx\n    pop ax\n    pop bp\n    ret 2\nwrite endp\n\n_print:\tmov si,0\n\tmov di,offset _buff_p\n_p_lp_1:\tmov al,ds:[bx+si]\n\tcmp al,0\n\tje _p_brk_1\n\tmov ds:[di],al\n\tinc si\n\tinc di\n\tjmp short _p_lp_1\n_p_brk_1:\tmov dx,offset _buff_p\n\tmov ah,09h\n\tint 21h\n\tmov cx,si\n\tmov di,offset _buff_p\n_p_lp_2:\tmov al,24h\n\tmov ds:[di],al\n\tinc di\n\tloop _p_lp_2\n\tret\ncode ends\nend start"
+	ASM_END = "code ends\nend start"
 )
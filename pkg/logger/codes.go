@@ -0,0 +1,118 @@
+package logger
+
+import "strings"
+
+// 诊断码: E00xx 词法错误, E01xx 语法错误, E02xx 语义错误, W02xx 语义警告, W03xx lint规则
+// 诊断码一经分配不再改变，新增的诊断只能使用新的编号
+const (
+	CodeIllegalToken = "E0001" // 不合法的token
+
+	CodeSyntax       = "E0100" // 其他语法错误
+	CodeMissingToken = "E0101" // 缺少符号或关键字
+	CodeMissingIdent = "E0102" // 缺少标识符
+	CodeMissingConst = "E0103" // 缺少常量
+	CodeMissingType  = "E0104" // 缺少类型
+	CodeMissingMain  = "E0105" // 缺少main函数
+	CodeAssignInCond = "E0106" // 条件中误用=
+	CodeNotLvalue    = "E0107" // 赋值号左边不是左值
+
+	CodeUndefinedVar   = "E0201" // 变量未定义
+	CodeRedeclaredVar  = "E0202" // 变量重复定义
+	CodeUnknownVarType = "E0203" // 变量类型未知
+	CodeScopeMismatch  = "E0204" // 变量作用域不匹配
+	CodeUndefinedType  = "E0205" // 类型未定义
+	CodeTypeMismatch   = "E0206" // 类型不匹配
+	CodeBadConversion  = "E0207" // 无法进行类型转换
+	CodeTernaryTypes   = "E0208" // 三目运算两个分支的类型不兼容
+	CodeCondType       = "E0209" // 判断条件的类型不能转换为bool
+
+	CodeRedeclaredConst = "E0210" // 常量重复定义
+	CodeConstNoValue    = "E0211" // 常量未赋值
+	CodeAssignConst     = "E0212" // 常量不可赋值
+	CodeConstAddress    = "E0213" // 常量不能取地址
+
+	CodeUndefinedFunc     = "E0220" // 函数未定义
+	CodeUndeclaredFunc    = "E0221" // 函数未声明
+	CodeRedefinedFunc     = "E0222" // 函数重复定义
+	CodeReturnType        = "E0223" // 函数返回值类型不匹配
+	CodeMissingReturnStmt = "E0224" // 函数缺少返回语句
+	CodeVoidOperand       = "E0225" // 没有返回值的函数不能参与运算
+	CodeArgCount          = "E0226" // 参数个数不匹配
+	CodeArgType           = "E0227" // 参数类型不匹配
+	CodeConflictingDecl   = "E0228" // 函数声明与之前的声明不一致
+	CodeDeclReturnType    = "E0229" // 函数返回类型与声明不一致
+
+	CodeDeclParams          = "E0230" // 函数形参与声明不一致
+	CodeRedefineBuiltin     = "E0231" // 不能重新定义内置函数
+	CodeFuncNotDefined      = "E0232" // 函数已声明但未定义
+	CodeVoidReturnValue     = "E0233" // void函数不能返回值
+	CodeMissingReturnValue  = "E0234" // 函数缺少返回值
+	CodeBreakOutsideLoop    = "E0235" // break语句不在循环中
+	CodeContinueOutsideLoop = "E0236" // continue语句不在循环中
+	CodeAssignFunc          = "E0237" // 函数名不可赋值
+
+	CodeRedefinedStruct = "E0240" // 结构体重复定义
+	CodeDuplicateField  = "E0241" // 结构体成员重复定义
+	CodeNoSuchField     = "E0242" // 结构体成员不存在
+	CodeStructInit      = "E0243" // 结构体变量不能初始化
+	CodeStructOperand   = "E0244" // 结构体变量不能直接参与运算
+
+	CodeRedefinedEnum      = "E0250" // 枚举重复定义
+	CodeDuplicateEnumConst = "E0251" // 枚举常量重复定义
+	CodeEnumNotInt         = "E0252" // 枚举值必须为整数
+
+	CodePointerMismatch = "E0260" // 指针类型不匹配
+	CodeDerefNonPointer = "E0261" // 只能对指针解引用
+	CodePointerMulDiv   = "E0262" // 指针不能参与乘除运算
+	CodePointerOperand  = "E0263" // 指针运算的操作数类型不合法
+
+	CodeConstDivByZero  = "E0270" // 除数不能为0
+	CodeConstMissing    = "E0271" // 缺少常量表达式
+	CodeNotConst        = "E0272" // 不是常量表达式
+	CodeConstField      = "E0273" // 常量表达式中不能访问结构体成员
+	CodeConstOperand    = "E0274" // 常量表达式中只能使用常量
+	CodeConstCall       = "E0275" // 常量表达式中不能调用函数
+	CodeConstConversion = "E0276" // 常量表达式中不能转换为类型
+	CodeConstPointer    = "E0277" // 常量表达式中不能使用指针运算
+	CodeConstOverflow   = "E0278" // 常量表达式溢出
+	CodeConstFloatMod   = "E0279" // 浮点数不能取模
+
+	CodeFloatMod = "E0280" // 取模运算的操作数不能是浮点数
+
+	CodeBadFormat   = "E0290" // 无效的格式说明符
+	CodeFormatType  = "E0291" // 格式说明符与实参类型不匹配
+	CodeFormatCount = "E0292" // 格式说明符与实参个数不匹配
+
+	CodeNarrowing           = "W0201" // 隐式转换可能丢失精度
+	CodeEnumRange           = "W0202" // 枚举值超出范围
+	CodeUnusedVar           = "W0203" // 变量未使用
+	CodeUnusedParam         = "W0204" // 形参未使用
+	CodeUnusedFunc          = "W0205" // 函数未使用
+	CodeShadow              = "W0206" // 变量遮蔽了外层的同名变量或全局常量
+	CodeTautologicalCompare = "W0207" // 比较结果恒为true或false
+	CodeUninitialized       = "W0208" // 变量未初始化
+	CodeMaybeUninitialized  = "W0209" // 变量可能未初始化
+
+	CodeUnreachable = "W0210" // 语句不可达
+	CodeDivByZero   = "W0211" // 除数为0
+	CodeOverflow    = "W0212" // 整数运算溢出
+
+	CodeMagicNumber  = "W0301" // 魔数
+	CodeDeepNesting  = "W0302" // 控制语句嵌套过深
+	CodeLongFunction = "W0303" // 函数过长
+	CodeEmptyLoop    = "W0304" // 循环体为空
+	CodeNaming       = "W0305" // 名字不符合命名规范
+)
+
+// missingToken 语法错误缺少的单个符号，用于生成修复建议
+func missingToken(msg string) string {
+	i := strings.LastIndex(msg, "缺少 ")
+	if i < 0 {
+		return ""
+	}
+	switch tok := strings.TrimSpace(msg[i+len("缺少 "):]); tok {
+	case ";", "(", ")", "{", "}", "=", ":":
+		return tok
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"complier/util"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Severity 诊断信息的严重程度
+type Severity int
+
+const (
+	SeverityError   Severity = iota // 错误
+	SeverityWarning                 // 警告
+	SeverityNote                    // 提示
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	}
+	return "error"
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Span 源程序中的一段位置，通常为一个token
+type Span struct {
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndColumn int    `json:"endColumn"`
+	Kind      int    `json:"kind"` //token的种别码
+	Text      string `json:"text"` //token值
+}
+
+// SpanOf 返回token所在的位置
+func SpanOf(token *util.TokenNode) Span {
+	return Span{
+		Line:      token.Pos.Line,
+		Column:    token.Pos.Column,
+		EndColumn: token.Pos.Column + utf8.RuneCountInString(token.Value),
+		Kind:      int(token.Type),
+		Text:      token.Value,
+	}
+}
+
+// Valid 位置是否有效，没有位置信息的诊断行号为0
+func (s Span) Valid() bool {
+	return s.Line > 0
+}
+
+// Related 与诊断相关的其他位置，如之前的声明
+type Related struct {
+	Span    Span   `json:"span"`
+	Message string `json:"message"`
+}
+
+// FixIt 修复建议，用Replacement替换Span中的内容，Span为空时表示插入
+type FixIt struct {
+	Span        Span   `json:"span"`
+	Replacement string `json:"replacement"`
+	Message     string `json:"message"`
+}
+
+// Diagnostic 一条诊断信息
+type Diagnostic struct {
+	Severity Severity  `json:"severity"`
+	Code     string    `json:"code"`           //稳定的诊断码，如E0201
+	Span     Span      `json:"span"`           //主要位置
+	Rule     string    `json:"rule,omitempty"` //语法错误时推断失败的非终结符
+	Message  string    `json:"message"`
+	Option   string    `json:"option,omitempty"` //控制该诊断的警告选项，如-Wunused-variable
+	Related  []Related `json:"related,omitempty"`
+	Fix      *FixIt    `json:"fix,omitempty"`
+}
+
+// Error 返回单行形式的诊断信息，如 3:5: error[E0201]: 变量未定义
+func (d *Diagnostic) Error() string {
+	msg := fmt.Sprintf("%s[%s]: %s", d.Severity, d.Code, d.text())
+	if d.Span.Valid() {
+		msg = fmt.Sprintf("%d:%d: %s", d.Span.Line, d.Span.Column, msg)
+	}
+	return msg
+}
+
+// text 诊断信息的正文，语法错误带上推断失败的非终结符
+func (d *Diagnostic) text() string {
+	if d.Rule != "" {
+		return d.Rule + "推断错误 " + d.message()
+	}
+	return d.message()
+}
+
+// message 诊断信息，由警告选项控制的诊断在后面注明选项
+func (d *Diagnostic) message() string {
+	if d.Option != "" {
+		return d.Message + " [" + d.Option + "]"
+	}
+	return d.Message
+}
+
+// WithRelated 添加一个相关位置，d为nil(如警告被关闭)时什么也不做
+func (d *Diagnostic) WithRelated(token *util.TokenNode, msg string) *Diagnostic {
+	if d != nil && token != nil {
+		d.Related = append(d.Related, Related{SpanOf(token), msg})
+	}
+	return d
+}
+
+// WithFix 添加修复建议
+func (d *Diagnostic) WithFix(span Span, replacement string, msg string) *Diagnostic {
+	d.Fix = &FixIt{span, replacement, msg}
+	return d
+}
@@ -2,25 +2,85 @@ package logger
 
 import (
 	"complier/util"
-	"fmt"
+	"sort"
+	"strings"
 )
 
+// Logger 诊断信息收集器，错误和警告分开保存
 type Logger struct {
-	Errs []string
+	Errs  []*Diagnostic
+	Warns []*Diagnostic //警告和提示
 }
 
 func NewLogger() *Logger {
 	return &Logger{}
 }
 
-func (l *Logger) AddErr(err string) {
-	l.Errs = append(l.Errs, err)
+// Report 按严重程度收集一条诊断信息
+func (l *Logger) Report(d *Diagnostic) *Diagnostic {
+	if d.Severity == SeverityError {
+		l.Errs = append(l.Errs, d)
+	} else {
+		l.Warns = append(l.Warns, d)
+	}
+	return d
 }
 
-func (l *Logger) AddParserErr(token util.TokenNode, nodeName string, msg ...string) {
-	l.AddErr(fmt.Sprintf("%d:%d\t\t%d\t\t%s\t\t%s推断错误 %s\n", token.Pos.Line, token.Pos.Column, token.Type, token.Value, nodeName, msg))
+// AddErr 添加没有位置信息的语义错误，code为诊断码
+func (l *Logger) AddErr(code string, err string) *Diagnostic {
+	return l.Report(&Diagnostic{Severity: SeverityError, Code: code, Message: err})
 }
 
-func (l *Logger) AddAnalyseErr(token *util.TokenNode, msg ...string) {
-	l.AddErr(fmt.Sprintf("%d:%d\t\t%d\t\t%s\t\t语义错误: %s\n", token.Pos.Line, token.Pos.Column, token.Type, token.Value, msg))
+// AddWarn 添加没有位置信息的语义警告，code为诊断码
+func (l *Logger) AddWarn(code string, warn string) *Diagnostic {
+	return l.Report(&Diagnostic{Severity: SeverityWarning, Code: code, Message: warn})
+}
+
+// AddLexErr 添加词法错误，token为不合法的识别结果
+func (l *Logger) AddLexErr(token util.TokenNode) *Diagnostic {
+	return l.Report(&Diagnostic{Severity: SeverityError, Code: CodeIllegalToken, Span: SpanOf(&token), Message: "不合法的token: " + token.Value})
+}
+
+// AddParserErr 添加语法错误，code为诊断码，缺少单个符号的错误生成插入该符号的修复建议
+func (l *Logger) AddParserErr(token util.TokenNode, nodeName string, code string, msg ...string) *Diagnostic {
+	text := strings.TrimSpace(strings.Join(msg, ""))
+	d := l.Report(&Diagnostic{Severity: SeverityError, Code: code, Span: SpanOf(&token), Rule: nodeName, Message: text})
+	if tok := missingToken(text); code == CodeMissingToken && tok != "" {
+		span := d.Span
+		span.EndColumn = span.Column
+		d.WithFix(span, tok, "插入 "+tok)
+	}
+	return d
+}
+
+// AddSyntaxErr 添加语法错误，不生成修复建议
+func (l *Logger) AddSyntaxErr(token util.TokenNode, nodeName string, code string, msg string) *Diagnostic {
+	return l.Report(&Diagnostic{Severity: SeverityError, Code: code, Span: SpanOf(&token), Rule: nodeName, Message: msg})
+}
+
+// AddAnalyseErr 添加语义错误，code为诊断码，由-Werror转为错误的警告使用警告的诊断码
+func (l *Logger) AddAnalyseErr(token *util.TokenNode, code string, msg ...string) *Diagnostic {
+	return l.Report(&Diagnostic{Severity: SeverityError, Code: code, Span: SpanOf(token), Message: strings.Join(msg, "")})
+}
+
+// AddAnalyseWarn 添加语义警告，code为诊断码
+func (l *Logger) AddAnalyseWarn(token *util.TokenNode, code string, msg ...string) *Diagnostic {
+	return l.Report(&Diagnostic{Severity: SeverityWarning, Code: code, Span: SpanOf(token), Message: strings.Join(msg, "")})
+}
+
+// Diagnostics 按位置排序的全部诊断信息，没有位置信息的排在最后
+func (l *Logger) Diagnostics() []*Diagnostic {
+	diags := make([]*Diagnostic, 0, len(l.Errs)+len(l.Warns))
+	diags = append(append(diags, l.Errs...), l.Warns...)
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Span, diags[j].Span
+		if a.Valid() != b.Valid() {
+			return a.Valid()
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diags
 }
@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"complier/util"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// label 文本形式中诊断信息的前缀
+func (d *Diagnostic) label() string {
+	switch {
+	case d.Severity == SeverityNote:
+		return "注: "
+	case d.Severity == SeverityWarning:
+		return "语义警告: "
+	case d.Rule != "":
+		return d.Rule + "推断错误 "
+	case strings.HasPrefix(d.Code, "E00"):
+		return "词法错误: "
+	case strings.HasPrefix(d.Code, "E01"):
+		return "语法错误: "
+	}
+	return "语义错误: "
+}
+
+// textLine 文本形式的一行: 行:列 种别码 token值 信息
+func textLine(span Span, msg string) string {
+	if !span.Valid() {
+		return "\t\t\t\t\t\t" + msg + "\n"
+	}
+	return fmt.Sprintf("%d:%d\t\t%d\t\t%s\t\t%s\n", span.Line, span.Column, span.Kind, span.Text, msg)
+}
+
+// RenderText 以制表符分隔的文本形式输出诊断信息，与界面中的表头对齐
+func RenderText(diags []*Diagnostic) string {
+	var b strings.Builder
+	for _, d := range diags {
+		b.WriteString(textLine(d.Span, d.label()+d.message()))
+		for _, r := range d.Related {
+			b.WriteString(textLine(r.Span, "注: "+r.Message))
+		}
+		if d.Fix != nil {
+			b.WriteString(textLine(d.Fix.Span, "建议: "+d.Fix.Message))
+		}
+	}
+	return b.String()
+}
+
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorRed    = "\033[1;31m"
+	colorYellow = "\033[1;33m"
+	colorCyan   = "\033[1;36m"
+	colorGreen  = "\033[1;32m"
+)
+
+func (s Severity) color() string {
+	switch s {
+	case SeverityWarning:
+		return colorYellow
+	case SeverityNote:
+		return colorCyan
+	}
+	return colorRed
+}
+
+// RenderColor 以带颜色的终端形式输出诊断信息，source不为空时在位置下方标出源代码
+func RenderColor(diags []*Diagnostic, source string) string {
+	lines := strings.Split(source, "\n")
+	if source == "" {
+		lines = nil
+	}
+	var b strings.Builder
+	for _, d := range diags {
+		if d.Span.Valid() {
+			fmt.Fprintf(&b, "%s%d:%d:%s ", colorBold, d.Span.Line, d.Span.Column, colorReset)
+		}
+		fmt.Fprintf(&b, "%s%s[%s]%s: %s%s%s\n", d.Severity.color(), d.Severity, d.Code, colorReset, colorBold, d.text(), colorReset)
+		snippet(&b, lines, d.Span, d.Severity.color())
+		for _, r := range d.Related {
+			fmt.Fprintf(&b, "%s%d:%d:%s %snote%s: %s\n", colorBold, r.Span.Line, r.Span.Column, colorReset, colorCyan, colorReset, r.Message)
+			snippet(&b, lines, r.Span, colorCyan)
+		}
+		if d.Fix != nil {
+			fmt.Fprintf(&b, "%shelp%s: %s\n", colorGreen, colorReset, d.Fix.Message)
+		}
+	}
+	return b.String()
+}
+
+// snippet 输出span所在的源代码行，并用^标出span的范围
+func snippet(b *strings.Builder, lines []string, span Span, color string) {
+	if !span.Valid() || span.Line > len(lines) {
+		return
+	}
+	line := strings.TrimRight(lines[span.Line-1], "\r")
+	fmt.Fprintf(b, "%5d | %s\n", span.Line, line)
+	width := span.EndColumn - span.Column
+	if width < 1 {
+		width = 1
+	}
+	pad := span.Column - 1
+	if n := utf8.RuneCountInString(line); pad > n {
+		pad = n
+	}
+	//保留制表符，保证^与源代码对齐
+	indent := []rune(line)[:max(pad, 0)]
+	for i, r := range indent {
+		if r != '\t' {
+			indent[i] = ' '
+		}
+	}
+	fmt.Fprintf(b, "      | %s%s%s%s\n", string(indent), color, strings.Repeat("^", width), colorReset)
+}
+
+// RenderJSON 以JSON数组形式输出诊断信息
+func RenderJSON(diags []*Diagnostic) ([]byte, error) {
+	if diags == nil {
+		diags = []*Diagnostic{}
+	}
+	return util.MarshalJSON(diags)
+}
+
+// SARIF 2.1.0 中用到的部分结构
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId           string          `json:"ruleId"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysical `json:"physicalLocation"`
+	Message          *sarifMessage `json:"message,omitempty"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifact      `json:"artifactLocation"`
+	Replacements     []sarifReplacement `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+// RenderSARIF 以SARIF 2.1.0格式输出诊断信息，uri为源文件路径
+func RenderSARIF(diags []*Diagnostic, uri string) ([]byte, error) {
+	region := func(s Span) sarifRegion {
+		return sarifRegion{s.Line, s.Column, max(s.EndColumn, s.Column)}
+	}
+	location := func(s Span) sarifPhysical {
+		return sarifPhysical{sarifArtifact{uri}, region(s)}
+	}
+
+	run := sarifRun{Tool: sarifTool{sarifDriver{Name: "complier"}}, Results: []sarifResult{}}
+	seen := map[string]bool{}
+	for _, d := range diags {
+		if !seen[d.Code] {
+			seen[d.Code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{d.Code})
+		}
+		res := sarifResult{RuleId: d.Code, Level: d.Severity.String(), Message: sarifMessage{d.text()}}
+		if d.Span.Valid() {
+			res.Locations = []sarifLocation{{PhysicalLocation: location(d.Span)}}
+		}
+		for _, r := range d.Related {
+			res.RelatedLocations = append(res.RelatedLocations, sarifLocation{location(r.Span), &sarifMessage{r.Message}})
+		}
+		if d.Fix != nil {
+			res.Fixes = []sarifFix{{sarifMessage{d.Fix.Message}, []sarifArtifactChange{{
+				sarifArtifact{uri},
+				[]sarifReplacement{{region(d.Fix.Span), sarifMessage{d.Fix.Replacement}}},
+			}}}}
+		}
+		run.Results = append(run.Results, res)
+	}
+	return util.MarshalJSON(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"log"
 	"os"
+	"strings"
 )
 
 type MenuHandler struct {
@@ -22,6 +23,7 @@ type MenuHandler struct {
 	Analyser     *compiler.Analyser // 语义分析器
 	QuaForm      *util.QuaFormList  // 四元式列表
 	Target       *compiler.Target   // 目标代码生成器
+	WarnFlags    string             // 警告选项，如 -Wall -Wno-shadow -Werror
 }
 
 func NewMenuHandler() *MenuHandler {
@@ -61,14 +63,16 @@ func (handler *MenuHandler) LexerHandler(input *widget.Entry, output *widget.Ent
 			pos, tokenid, token, lexerr := lexer.Lex()
 
 			if tokenid == consts.ILLEGAL { //当前识别结果不合法
-				lexLogger.AddErr(fmt.Sprintf("%d:%d\t\t%d\t\t%s\n", pos.Line, pos.Column, tokenid, token))
+				lexLogger.AddLexErr(util.TokenNode{Pos: pos, Type: tokenid, Value: token})
 			}
 
 			if tokenid == consts.EOF || lexerr != nil {
 				break
 			}
 
-			if tokenid != consts.TokenMap["//"] && tokenid != consts.TokenMap["/**/"] && tokenid != consts.ILLEGAL { //忽略注释和错误
+			if tokenid == consts.TokenMap["//"] || tokenid == consts.TokenMap["/**/"] { //注释中可能有nowarn指令
+				handler.Parser.Comments = append(handler.Parser.Comments, util.TokenNode{Pos: pos, Type: tokenid, Value: token})
+			} else if tokenid != consts.ILLEGAL { //忽略错误
 				result = result + fmt.Sprintf("%d:%d\t\t%d\t\t\t%s\n", pos.Line, pos.Column, tokenid, token)
 				handler.Parser.Token = append(handler.Parser.Token, util.TokenNode{Pos: pos, Type: tokenid, Value: token})
 			}
@@ -76,9 +80,7 @@ func (handler *MenuHandler) LexerHandler(input *widget.Entry, output *widget.Ent
 		}
 		output.SetText(result)
 		msg := fmt.Sprintf("---------词法分析完成---------\n%d error(s)\n", len(lexLogger.Errs))
-		for _, e := range lexLogger.Errs {
-			msg += e
-		}
+		msg += logger.RenderText(lexLogger.Errs)
 		bottomOutput.SetText(msg)
 		if len(lexLogger.Errs) == 0 { //词法分析结束且没有错误
 			handler.LexerFlag = true
@@ -108,9 +110,7 @@ func (handler *MenuHandler) ParserHandler(input *widget.Entry, output *widget.En
 
 		if errs != 0 {
 			msg += fmt.Sprintf("行:列\t\t种别码\ttoken值\t错误信息\n")
-			for _, err := range handler.Parser.Logger.Errs {
-				msg += err
-			}
+			msg += logger.RenderText(handler.Parser.Logger.Errs)
 		}
 
 		bottomOutput.SetText(msg)
@@ -129,19 +129,29 @@ func (handler *MenuHandler) AnalysierHandler(input *widget.Entry, output *widget
 			return
 		}
 		handler.Analyser = compiler.NewAnalyser(handler.Parser.AST)
+		handler.Analyser.SetComments(handler.Parser.Comments)
+		if err := handler.Analyser.Warnings.Parse(handler.WarnFlags); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		linter, err := compiler.LoadLinter(compiler.LintConfigFile)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
 		handler.Analyser.StartAnalyse()
+		handler.Analyser.Lint(linter)
 		handler.QuaForm = handler.Analyser.Qf
 		result := handler.Analyser.SymbolTable.String() + "\n\n" + handler.Analyser.Qf.PrintQuaFormList()
 		output.SetText(result)
 
 		errs := len(handler.Analyser.Logger.Errs)
-		msg := fmt.Sprintf("---------语义分析完成---------\n%d error(s)\n\n", errs)
+		warns := len(handler.Analyser.Logger.Warns)
+		msg := fmt.Sprintf("---------语义分析完成---------\n%d error(s), %d warning(s)\n\n", errs, warns)
 
-		if errs != 0 {
+		if errs != 0 || warns != 0 {
 			msg += fmt.Sprintf("行:列\t\t种别码\ttoken值\t错误信息\n")
-			for _, err := range handler.Analyser.Logger.Errs {
-				msg += err
-			}
+			msg += logger.RenderText(handler.Analyser.Logger.Diagnostics())
 		}
 
 		bottomOutput.SetText(msg)
@@ -152,7 +162,42 @@ func (handler *MenuHandler) AnalysierHandler(input *widget.Entry, output *widget
 		}
 		content := handler.Analyser.SymbolTable.String()
 		path := fmt.Sprintf("pkg/saveFile/test/%s_symbol.txt", util.GetTIme())
-		err := util.SaveFile(content, path)
+		err = util.SaveFile(content, path)
+		if err != nil {
+			log.Print(err.Error())
+		}
+
+		symbols, err := handler.Analyser.SymbolTable.JSON()
+		if err == nil {
+			err = util.SaveFile(string(symbols), fmt.Sprintf("pkg/saveFile/test/%s_symbol.json", util.GetTIme()))
+		}
+		if err != nil {
+			log.Print(err.Error())
+		}
+		symbols, err = handler.Analyser.SymbolTable.CSV()
+		if err == nil {
+			err = util.SaveFile(string(symbols), fmt.Sprintf("pkg/saveFile/test/%s_symbol.csv", util.GetTIme()))
+		}
+		if err != nil {
+			log.Print(err.Error())
+		}
+
+		content = handler.Analyser.Xref.Report()
+		path = fmt.Sprintf("pkg/saveFile/test/%s_xref.txt", util.GetTIme())
+		err = util.SaveFile(content, path)
+		if err != nil {
+			log.Print(err.Error())
+		}
+
+		calls := handler.Analyser.CallGraph()
+		err = util.SaveFile(calls.DOT(), fmt.Sprintf("pkg/saveFile/test/%s_callgraph.dot", util.GetTIme()))
+		if err != nil {
+			log.Print(err.Error())
+		}
+		graph, err := calls.JSON()
+		if err == nil {
+			err = util.SaveFile(string(graph), fmt.Sprintf("pkg/saveFile/test/%s_callgraph.json", util.GetTIme()))
+		}
 		if err != nil {
 			log.Print(err.Error())
 		}
@@ -163,6 +208,27 @@ func (handler *MenuHandler) AnalysierHandler(input *widget.Entry, output *widget
 		if err != nil {
 			log.Print(err.Error())
 		}
+
+		diags := handler.Analyser.Logger.Diagnostics()
+		fmt.Fprint(os.Stderr, logger.RenderColor(diags, input.Text)) //从终端启动时在终端中输出带颜色的诊断信息
+		diagJSON, err := logger.RenderJSON(diags)
+		if err == nil {
+			err = util.SaveFile(string(diagJSON), fmt.Sprintf("pkg/saveFile/test/%s_diag.json", util.GetTIme()))
+		}
+		if err != nil {
+			log.Print(err.Error())
+		}
+
+		sarif, err := logger.RenderSARIF(diags, "pkg/temp/temp.txt")
+		if err != nil {
+			log.Print(err.Error())
+			return
+		}
+		path = fmt.Sprintf("pkg/saveFile/test/%s_diag.sarif", util.GetTIme())
+		err = util.SaveFile(string(sarif), path)
+		if err != nil {
+			log.Print(err.Error())
+		}
 	}
 }
 
@@ -182,9 +248,7 @@ func (handler *MenuHandler) TargetHandler(input *widget.Entry, output *widget.En
 
 		if errs != 0 {
 			msg += fmt.Sprintf("行:列\t\t种别码\ttoken值\t错误信息\n")
-			for _, err := range handler.Analyser.Logger.Errs {
-				msg += err
-			}
+			msg += logger.RenderText(handler.Analyser.Logger.Errs)
 		}
 		msg += "\n\n" + handler.Analyser.Qf.PrintQuaFormList()
 		bottomOutput.SetText(msg)
@@ -200,6 +264,51 @@ func (handler *MenuHandler) TargetHandler(input *widget.Entry, output *widget.En
 	}
 }
 
+// WarnFlagsHandler 设置语义分析的警告选项
+func (handler *MenuHandler) WarnFlagsHandler(window fyne.Window) func() {
+	return func() {
+		flags := widget.NewEntry()
+		flags.SetText(handler.WarnFlags)
+		flags.SetPlaceHolder("-Wall -Wno-shadow -Werror")
+		items := []*widget.FormItem{widget.NewFormItem("警告选项", flags)}
+		dialog.ShowForm("警告选项", "确定", "取消", items, func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := compiler.NewWarningOptions().Parse(flags.Text); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			handler.WarnFlags = flags.Text
+		}, window)
+	}
+}
+
+// InterpretHandler 解释执行四元式，输入的整数每行一个
+func (handler *MenuHandler) InterpretHandler(input *widget.Entry, output *widget.Entry, bottomOutput *widget.Entry, window fyne.Window) func() {
+	return func() {
+		if !handler.AnalyserFlag {
+			dialog.ShowInformation("解释执行", "请先运行通过语义分析！", window)
+			return
+		}
+		stdin := widget.NewMultiLineEntry()
+		stdin.SetPlaceHolder("程序的输入，每行一个整数")
+		items := []*widget.FormItem{widget.NewFormItem("输入", stdin)}
+		dialog.ShowForm("解释执行", "运行", "取消", items, func(ok bool) {
+			if !ok {
+				return
+			}
+			var out strings.Builder
+			err := compiler.NewInterpreter(handler.QuaForm, handler.Analyser.SymbolTable, strings.NewReader(stdin.Text), &out).Run()
+			msg := "---------解释执行完成---------\n"
+			if err != nil {
+				msg = "---------解释执行出错---------\n" + err.Error() + "\n"
+			}
+			bottomOutput.SetText(msg + out.String())
+		}, window)
+	}
+}
+
 func (handler *MenuHandler) AlgorithmHandler(myApp fyne.App, input *widget.Entry, output *widget.Entry, bottomOutput *widget.Entry, window fyne.Window) func() {
 	return func() {
 		mainWindow := myApp.NewWindow("DAG Window")
@@ -222,11 +331,19 @@ func (handler *MenuHandler) AlgorithmHandler(myApp fyne.App, input *widget.Entry
 				dialog.ShowInformation("DAG优化", "请输入四元式代码", window)
 				return
 			}
+			if err := dag.Warnings.Parse(handler.WarnFlags); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
 			//  2. 调用 DAG 优化函数，传入四元式代码
 			dag.StartDAG(inputQf)
 			//  3. 将优化后的四元式代码分别显示在右边的两个输入框中
 			topRightEntry.SetText(dag.PrintBasicBlocks())
 			bottomRightEntry.SetText(dag.DAGQf.PrintQuaFormList())
+			//  4. 优化中发现的问题(如除数为0)显示在主窗口下方
+			if diags := dag.Logger.Diagnostics(); len(diags) > 0 {
+				bottomOutput.SetText(logger.RenderText(diags))
+			}
 
 			content := dag.DAGQf.PrintQuaFormList()
 			path := fmt.Sprintf("pkg/saveFile/test/%s_dag.txt", util.GetTIme())
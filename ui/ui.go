@@ -107,6 +107,7 @@ func InitApp() {
 
 	analysierMenu := fyne.NewMenu("语义分析",
 		fyne.NewMenuItem("语义分析器", menuHandler.AnalysierHandler(leftInput, rightOutput, bottomOutput, MainWindow)),
+		fyne.NewMenuItem("警告选项", menuHandler.WarnFlagsHandler(MainWindow)),
 	)
 
 	//IRcodeMenu := fyne.NewMenu("中间代码",
@@ -116,6 +117,7 @@ func InitApp() {
 
 	targetcodeMenu := fyne.NewMenu("目标代码",
 		fyne.NewMenuItem("目标代码生成器", menuHandler.TargetHandler(leftInput, rightOutput, bottomOutput, MainWindow)),
+		fyne.NewMenuItem("解释执行", menuHandler.InterpretHandler(leftInput, rightOutput, bottomOutput, MainWindow)),
 	)
 
 	//TODO：完善相关算法菜单选项函数
@@ -0,0 +1,18 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MarshalJSON 缩进输出JSON，不转义<、>和&，以免非终结符和运算符变得难以阅读，结尾没有换行
+func MarshalJSON(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
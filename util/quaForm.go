@@ -11,6 +11,7 @@ type QuaForm struct {
 	Arg1   any
 	Arg2   any
 	Result any
+	Token  *TokenNode // 生成该四元式的运算符在源程序中的位置，用于诊断，可以为nil
 }
 
 // NewQuaForm 创建四元式
@@ -40,6 +41,17 @@ func NewForJmpPos() *ForJmpPos {
 	}
 }
 
+// FieldRef 结构体成员的引用，作为赋值运算的左值
+type FieldRef struct {
+	Name   string //结构体变量名
+	Offset int    //成员相对结构体变量起始地址的偏移
+}
+
+// DerefRef 指针所指的存储单元，作为赋值运算的左值
+type DerefRef struct {
+	Addr any //保存地址的变量
+}
+
 // QuaFormList 四元式列表
 type QuaFormList struct {
 	QuaForms             []*QuaForm
@@ -78,6 +90,7 @@ func (q *QuaFormList) ClearBreakStack(id int) {
 		q.QuaForms[top].Result = id
 	}
 	q.BreakStacks.Pop()
+	q.CurrentBreakStack = nil
 	if q.BreakStacks.Top() != nil {
 		q.CurrentBreakStack = q.BreakStacks.Top().(*Stack[any])
 	}
@@ -96,6 +109,7 @@ func (q *QuaFormList) ClearContinueStack(id int) {
 		q.QuaForms[top].Result = id
 	}
 	q.ContinueStacks.Pop()
+	q.CurrentContinueStack = nil
 	if q.ContinueStacks.Top() != nil {
 		q.CurrentContinueStack = q.ContinueStacks.Top().(*Stack[any])
 	}
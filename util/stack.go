@@ -90,9 +90,10 @@ type CalStack struct {
 	OpStack    *Stack[any]
 	qf         *QuaFormList
 	Result     any
-	IfQuaStack *Stack[any] //if四元式栈，这个栈放的是一个if语句结束后需要跳转的四元式，跳转到一个完整的if语句的结束位置
-	currentOp  any         //当前运算符
-	LogicStack *LogicStack //逻辑栈
+	IfQuaStack *Stack[any]        //if四元式栈，这个栈放的是一个if语句结束后需要跳转的四元式，跳转到一个完整的if语句的结束位置
+	currentOp  any                //当前运算符
+	LogicStack *LogicStack        //逻辑栈
+	opTokens   map[int]*TokenNode //运算符栈中各层运算符在源程序中的位置
 }
 
 // NewCalStack 创建计算栈
@@ -102,6 +103,7 @@ func NewCalStack(qf *QuaFormList) *CalStack {
 		OpStack:    NewStack(),
 		qf:         qf,
 		LogicStack: NewLogicStack(qf),
+		opTokens:   make(map[int]*TokenNode),
 	}
 }
 
@@ -202,6 +204,23 @@ func (c *CalStack) PushOp(ope int) {
 		top = c.OpStack.Top()
 	}
 	c.OpStack.Push(ope)
+	delete(c.opTokens, c.OpStack.Size()-1)
+}
+
+// PushOpAt 入操作符栈并记录运算符的位置，运算符生成的四元式带有该位置
+func (c *CalStack) PushOpAt(ope int, token *TokenNode) {
+	c.PushOp(ope)
+	if c.OpStack.Top() == ope {
+		c.opTokens[c.OpStack.Size()-1] = token
+	}
+}
+
+// popToken 取出栈顶运算符的位置
+func (c *CalStack) popToken() *TokenNode {
+	i := c.OpStack.Size() - 1
+	token := c.opTokens[i]
+	delete(c.opTokens, i)
+	return token
 }
 
 // PushIfOp 入操作符栈
@@ -223,6 +242,7 @@ func (c *CalStack) PushIfOp(ope int) {
 	//&&和||运算符不入栈
 	if ope != consts.QUA_AND && ope != consts.QUA_OR {
 		c.OpStack.Push(ope)
+		delete(c.opTokens, c.OpStack.Size()-1)
 	} else if c.qf.IfFlag && c.qf.RelaOp == false {
 		c.OpStack.Push(consts.QUA_NORELA)
 		c.Cal()
@@ -235,6 +255,7 @@ func (c *CalStack) Cal() {
 		c.CalIf()
 		return
 	}
+	token := c.popToken()
 	op := c.OpStack.Pop()
 	num2 := c.NumStack.Pop()
 	if consts.QUA_NOT == op || consts.QUA_NEGATIVE == op {
@@ -269,18 +290,23 @@ func (c *CalStack) Cal() {
 	num1 := c.NumStack.Pop()
 	// 遇到赋值运算符，num1为变量，num2为值
 	if op == consts.QUA_ASSIGNMENT {
+		if c.assignRef(num1, num2) {
+			return
+		}
 		c.qf.AddQuaForm(consts.QuaFormMap[op.(int)], num2, nil, num1)
 		c.Result = num2
 		return
 	}
 
 	result := c.qf.GetTemp()
-	c.qf.AddQuaForm(consts.QuaFormMap[op.(int)], num1, num2, result)
+	id := c.qf.AddQuaForm(consts.QuaFormMap[op.(int)], num1, num2, result)
+	c.qf.QuaForms[id].Token = token
 	c.NumStack.Push(result)
 }
 
 // CalIf 对数字栈顶两个元素进行一次计算,遇到"#","@","!"只取栈顶一个元素进行一次计算
 func (c *CalStack) CalIf() {
+	token := c.popToken()
 	op := c.OpStack.Pop()
 
 	if op == consts.QUA_MOVE {
@@ -347,6 +373,9 @@ func (c *CalStack) CalIf() {
 	num1 := c.NumStack.Pop()
 	// 遇到赋值运算符，num1为变量，num2为值
 	if op == consts.QUA_ASSIGNMENT {
+		if c.assignRef(num1, num2) {
+			return
+		}
 		c.qf.AddQuaForm(consts.QuaFormMap[op.(int)], num2, nil, num1)
 		c.Result = num2
 		return
@@ -376,7 +405,8 @@ func (c *CalStack) CalIf() {
 	}
 
 	result := c.qf.GetTemp()
-	c.qf.AddQuaForm(consts.QuaFormMap[op.(int)], num1, num2, result)
+	id := c.qf.AddQuaForm(consts.QuaFormMap[op.(int)], num1, num2, result)
+	c.qf.QuaForms[id].Token = token
 	c.NumStack.Push(result)
 
 }
@@ -453,6 +483,7 @@ func (c *CalStack) CalAllUtilReturn() {
 func (c *CalStack) Clear() {
 	c.NumStack = NewStack()
 	c.OpStack = NewStack()
+	c.opTokens = make(map[int]*TokenNode)
 }
 
 func (c *CalStack) ClearCurrentIfStack() {
@@ -537,6 +568,11 @@ func (c *CalStacks) PushOpe(ope int) {
 	c.CurrentStack.PushOp(ope)
 }
 
+// PushOpeAt 入操作符栈并记录运算符的位置
+func (c *CalStacks) PushOpeAt(ope int, token *TokenNode) {
+	c.CurrentStack.PushOpAt(ope, token)
+}
+
 func (c *CalStacks) CalIf() {
 	c.CurrentStack.Cal() // 计算一次move操作
 
@@ -609,3 +645,17 @@ func (c *CalStacks) ClearTrueStack(id int) {
 func (c *CalStacks) ClearFalseStack(id int) {
 	c.CurrentLogicStack.ClearFalseStack(id)
 }
+
+// assignRef 左值为结构体成员或指针所指的单元时生成对应的写入四元式，返回是否已处理
+func (c *CalStack) assignRef(target, value any) bool {
+	switch ref := target.(type) {
+	case *FieldRef:
+		c.qf.AddQuaForm(consts.QuaFormMap[consts.QUA_FIELDSET], value, ref.Offset, ref.Name)
+	case *DerefRef:
+		c.qf.AddQuaForm(consts.QuaFormMap[consts.QUA_DEREFSET], value, nil, ref.Addr)
+	default:
+		return false
+	}
+	c.Result = value
+	return true
+}
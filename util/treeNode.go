@@ -19,6 +19,10 @@ type TreeNode struct {
 }
 
 func NewTreeNode(token *TokenNode, value string) *TreeNode {
+	if token != nil { //语法分析器会复用同一个token变量，保存一份副本，避免节点的位置被之后读入的token覆盖
+		t := *token
+		token = &t
+	}
 	return &TreeNode{Token: token, Value: value}
 }
 